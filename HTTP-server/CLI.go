@@ -37,10 +37,25 @@ func (cli *CLI) PlayPoker() {
 	}
 	cli.game.Start(numberOfPlayers)
 
-	winnerInput := cli.readLine()
-	winner := extractWinner(winnerInput)
+	finishing := extractFinishingOrder(cli.readLine())
+	if len(finishing) > 1 {
+		cli.game.FinishRanking(finishing)
+		return
+	}
+	cli.game.Finish(finishing[0])
+}
 
-	cli.game.Finish(winner)
+// extractFinishingOrder reads "{Name} wins" or, to record where everyone
+// else finished too, "{Name} wins, {second}, {third}..."
+func extractFinishingOrder(userInput string) []string {
+	names := strings.Split(userInput, ",")
+	finishing := []string{extractWinner(names[0])}
+	for _, name := range names[1:] {
+		if name = strings.TrimSpace(name); name != "" {
+			finishing = append(finishing, name)
+		}
+	}
+	return finishing
 }
 
 func extractWinner(userInput string) string {
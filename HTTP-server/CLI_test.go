@@ -4,6 +4,7 @@ import (
 	poker "HTTP-server"
 	"bytes"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ type GameSpy struct {
 	StartCalled  bool
 	StartedWith  int
 	FinishedWith string
+	RankedWith   []string
 }
 
 func (g *GameSpy) Start(numberOfPlayers int) {
@@ -24,6 +26,11 @@ func (g *GameSpy) Finish(winner string) {
 	g.FinishedWith = winner
 }
 
+func (g *GameSpy) FinishRanking(finishing []string) {
+	g.FinishedWith = finishing[0]
+	g.RankedWith = finishing
+}
+
 type scheduledAlert struct {
 	at     time.Duration
 	amount int
@@ -129,6 +136,32 @@ func TestGame_Finish(t *testing.T) {
 		if game.FinishedWith != winner {
 			t.Errorf("expected finish called with 'Chris' but got %q", game.FinishedWith)
 		}
+		if game.RankedWith != nil {
+			t.Errorf("expected only the winner but got finishing order %v", game.RankedWith)
+		}
+	})
+	t.Run("finishes game with the whole finishing order", func(t *testing.T) {
+		in := strings.NewReader("3\nChris wins, Cleo , Floyd,\n")
+		game := &GameSpy{}
+		cli := poker.NewCLI(in, dummyStdOut, game)
+		cli.PlayPoker()
+
+		want := []string{"Chris", "Cleo", "Floyd"}
+		if !reflect.DeepEqual(game.RankedWith, want) {
+			t.Errorf("expected finish called with %v but got %v", want, game.RankedWith)
+		}
+	})
+	t.Run("records the finishing order of a poker game", func(t *testing.T) {
+		in := strings.NewReader("4\nChris wins, Cleo, Floyd\n")
+		store := &poker.StubPlayerStore{}
+		cli := poker.NewCLI(in, dummyStdOut, poker.NewPokerGame(dummyBlindAlerter, store))
+		cli.PlayPoker()
+
+		poker.AssertPlayerWin(t, store, "Chris")
+		want := []string{"Chris", "Cleo", "Floyd"}
+		if len(store.Games) != 1 || !reflect.DeepEqual(store.Games[0].Finishing, want) || store.Games[0].Players != 4 {
+			t.Errorf("expected a game of 4 finishing %v, got %+v", want, store.Games)
+		}
 	})
 }
 
@@ -0,0 +1,103 @@
+package poker
+
+import (
+	"context"
+	"time"
+)
+
+// AuditAction is the kind of change an AuditEntry records
+type AuditAction string
+
+const (
+	AuditRename  AuditAction = "rename"
+	AuditMerge   AuditAction = "merge"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditPurge   AuditAction = "purge"
+	// AuditUndo is From the winners of the games undone and To the ID of
+	// the Undo keeping them
+	AuditUndo AuditAction = "undo"
+	// AuditRedo is From the winners of the games put back and To the ID of
+	// the Undo they came from
+	AuditRedo AuditAction = "redo"
+	// AuditImport is To the ImportMode of the import
+	AuditImport AuditAction = "import"
+)
+
+// AuditEntry records a change made to the players of a League
+type AuditEntry struct {
+	ID     int         `json:"id"`
+	Time   time.Time   `json:"time"`
+	Action AuditAction `json:"action"`
+	// Actor is who made the change, as given to WithActor
+	Actor string `json:"actor,omitempty"`
+	// From are the players changed and To the player they became
+	From []string `json:"from"`
+	To   string   `json:"to,omitempty"`
+}
+
+// AuditStore is implemented by stores that keep an audit log
+type AuditStore interface {
+	// GetAuditLog returns every change, oldest first
+	GetAuditLog(ctx context.Context) ([]AuditEntry, error)
+}
+
+// AuditFilter picks entries out of the audit log, zero fields match everything
+type AuditFilter struct {
+	// Player matches entries changing or creating the player
+	Player string
+	Action AuditAction
+}
+
+func (f AuditFilter) Matches(entry AuditEntry) bool {
+	if f.Action != "" && entry.Action != f.Action {
+		return false
+	}
+	if f.Player == "" || samePlayer(entry.To, f.Player) {
+		return true
+	}
+	for _, name := range entry.From {
+		if samePlayer(name, f.Player) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterAudit returns the entries in audit that match filter
+func FilterAudit(audit []AuditEntry, filter AuditFilter) []AuditEntry {
+	matching := []AuditEntry{}
+	for _, entry := range audit {
+		if filter.Matches(entry) {
+			matching = append(matching, entry)
+		}
+	}
+	return matching
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx naming who is making changes through it,
+// stores record them as the Actor of their audit entries
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, if any
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+func (d *playerDB) audit(ctx context.Context, action AuditAction, from []string, to string) AuditEntry {
+	entry := AuditEntry{
+		ID:     len(d.Audit) + 1,
+		Time:   time.Now().UTC(),
+		Action: action,
+		Actor:  ActorFromContext(ctx),
+		From:   append([]string(nil), from...),
+		To:     to,
+	}
+	d.Audit = append(d.Audit, entry)
+	return entry
+}
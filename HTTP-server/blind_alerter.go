@@ -3,6 +3,8 @@ package poker
 import (
 	"fmt"
 	"os"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -21,3 +23,102 @@ func StdOutAlerter(duration time.Duration, amount int) {
 		fmt.Fprintf(os.Stdout, "Blind is now %d\n", amount)
 	})
 }
+
+// wsBlindAlerter sends each blind of a game over the websocket protocol as
+// it's due, with send, which a room points at everyone following the game.
+// Blinds scheduled before start are held until it's called, so that each
+// alert can say when the next blind is due.
+type wsBlindAlerter struct {
+	send func(message WSMessage)
+
+	mu       sync.Mutex
+	schedule []scheduledBlind
+	started  time.Time
+	timers   []*time.Timer
+	stopped  bool
+}
+
+type scheduledBlind struct {
+	at     time.Duration
+	amount int
+}
+
+func newWSBlindAlerter(send func(message WSMessage)) *wsBlindAlerter {
+	return &wsBlindAlerter{send: send}
+}
+
+func (a *wsBlindAlerter) ScheduleAlertAt(at time.Duration, amount int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.schedule = append(a.schedule, scheduledBlind{at, amount})
+	sort.SliceStable(a.schedule, func(i, j int) bool { return a.schedule[i].at < a.schedule[j].at })
+	if !a.started.IsZero() && !a.stopped {
+		a.timers = append(a.timers, a.alertAt(at))
+	}
+}
+
+// start sends each blind scheduled so far when it's due
+func (a *wsBlindAlerter) start() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.started.IsZero() || a.stopped {
+		return
+	}
+	a.started = time.Now()
+	for _, blind := range a.schedule {
+		a.timers = append(a.timers, a.alertAt(blind.at))
+	}
+}
+
+func (a *wsBlindAlerter) alertAt(at time.Duration) *time.Timer {
+	return time.AfterFunc(time.Until(a.started.Add(at)), func() {
+		a.mu.Lock()
+		if a.stopped {
+			a.mu.Unlock()
+			return
+		}
+		message := a.blindAt(at)
+		a.mu.Unlock()
+		a.send(message)
+	})
+}
+
+// blindAt is the alert for the blind due at, with the next one after it
+func (a *wsBlindAlerter) blindAt(at time.Duration) WSMessage {
+	message := WSMessage{Type: MsgBlind}
+	for i, blind := range a.schedule {
+		if blind.at <= at {
+			message.Amount, message.Level = blind.amount, i+1
+			continue
+		}
+		message.NextAmount = blind.amount
+		message.NextIn = time.Until(a.started.Add(blind.at)).Milliseconds()
+		break
+	}
+	return message
+}
+
+// current is the alert for the blind in play now, once the first is due
+func (a *wsBlindAlerter) current() (WSMessage, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.started.IsZero() || a.stopped || len(a.schedule) == 0 {
+		return WSMessage{}, false
+	}
+	elapsed := time.Since(a.started)
+	if elapsed < a.schedule[0].at {
+		return WSMessage{}, false
+	}
+	return a.blindAt(elapsed), true
+}
+
+// stop cancels every blind not yet sent
+func (a *wsBlindAlerter) stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stopped = true
+	for _, timer := range a.timers {
+		timer.Stop()
+	}
+	a.timers = nil
+}
@@ -2,21 +2,35 @@ package main
 
 import (
 	poker "HTTP-server"
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 )
 
-const dbFileName = "game.db.json"
+var storeFlags = poker.AddStoreFlags(flag.CommandLine)
 
 func main() {
-	store, closeFunc, err := poker.FileSystemPlayerStoreFromFile(dbFileName)
+	flag.Parse()
+	store, closeFunc, err := storeFlags.Open()
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer closeFunc()
+
+	if flag.NArg() > 0 {
+		ctx := poker.WithActor(context.Background(), "cli:"+os.Getenv("USER"))
+		if err := poker.RunCommand(ctx, store, os.Stdout, flag.Args()); err != nil {
+			fmt.Fprint(os.Stderr, poker.CommandUsage)
+			log.Fatal(err)
+		}
+		return
+	}
+
 	fmt.Println("Let's play poker")
 	fmt.Println("Type {Name} wins to record a win")
+	fmt.Println("or {Name} wins, {second}, {third}... to record the finishing order")
 	game := poker.NewPokerGame(poker.BlindAlerterFunc(poker.StdOutAlerter), store)
 	cli := poker.NewCLI(os.Stdin, os.Stdout, game)
 	cli.PlayPoker()
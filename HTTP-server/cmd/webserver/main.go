@@ -2,24 +2,61 @@ package main
 
 import (
 	poker "HTTP-server"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 )
 
-const (
-	dbFileName   = "game.db.json"
-	localHostUrl = "http://localhost:5000"
+const localHostUrl = "http://localhost:5000"
+
+var (
+	storeFlags       = poker.AddStoreFlags(flag.CommandLine)
+	leagueDir        = flag.String("leagues", "leagues", "directory keeping the named leagues, not supported by the postgres store")
+	adminToken       = flag.String("admin-token", os.Getenv("POKER_ADMIN_TOKEN"), "bearer token for admin only routes, defaults to $POKER_ADMIN_TOKEN, admin routes are disabled without one")
+	idempotency      = flag.Duration("idempotency-window", poker.DefaultIdempotencyWindow, "how long Idempotency-Keys and websocket game IDs are remembered, 0 turns them off")
+	idempotencyLimit = flag.Int("idempotency-limit", poker.DefaultIdempotencyLimit, "how many Idempotency-Keys and websocket game IDs are remembered at once, 0 turns them off")
+	heartbeat        = flag.Duration("heartbeat", poker.DefaultHeartbeat, "how often websockets are pinged, clients missing two pings are dropped, 0 turns pings off")
+	resume           = flag.Duration("resume-window", poker.DefaultResumeWindow, "how long a dropped websocket session can be resumed, 0 turns resuming off")
+	sessionLimit     = flag.Int("session-limit", poker.DefaultSessionLimit, "how many websocket sessions are kept at once, including those waiting to be resumed, 0 keeps any number")
+	roomLimit        = flag.Int("room-limit", poker.DefaultRoomLimit, "how many game rooms can be open at once, 0 lets any number be opened")
+	roomIdle         = flag.Duration("room-idle-timeout", poker.DefaultRoomIdleTimeout, "how long a game room stays open with nobody in it, 0 keeps empty rooms open")
 )
 
 func main() {
-	store, closeFunc, err := poker.FileSystemPlayerStoreFromFile(dbFileName)
+	flag.Parse()
+	store, closeFunc, err := storeFlags.Open()
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer closeFunc()
 
-	server, _ := poker.NewPlayerServer(store)
+	leagues, err := storeFlags.OpenLeagues(*leagueDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	options := []poker.ServerOption{
+		poker.WithAdminToken(*adminToken),
+		poker.WithIdempotencyWindow(*idempotency),
+		poker.WithIdempotencyLimit(*idempotencyLimit),
+		poker.WithHeartbeat(*heartbeat),
+		poker.WithResumeWindow(*resume),
+		poker.WithSessionLimit(*sessionLimit),
+		poker.WithRoomLimit(*roomLimit),
+		poker.WithRoomIdleTimeout(*roomIdle),
+	}
+	var server *poker.PlayerServer
+	if leagues == nil {
+		server, err = poker.NewPlayerServer(store, options...)
+	} else {
+		defer leagues.Close()
+		server, err = poker.NewLeagueServer(store, leagues, options...)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 	fmt.Printf("listening on %s\n", localHostUrl)
 	if err := http.ListenAndServe(":5000", server); err != nil {
 		log.Fatalf("could not listen on port 5000 %v", err)
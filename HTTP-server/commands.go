@@ -0,0 +1,204 @@
+package poker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownCommand is returned by RunCommand for a command it doesn't know
+var ErrUnknownCommand = errors.New("unknown command, want rename, merge, undo, import or export")
+
+// CommandUsage describes the commands RunCommand knows
+const CommandUsage = `commands:
+  rename {from} {to}          give a player a new name
+  merge {into} {from}...      merge players into one
+  undo [n]                    take back the last game recorded, or the last n
+  import [-replace] [-dry-run] {file}
+                              import players and games from a .json or .csv
+                              file, merging them in unless -replace is given
+  export [-csv games|carried] [file]
+                              export games and carried over wins as JSON, or
+                              one of them as CSV, to file or the output
+`
+
+// RunCommand runs one of the maintenance commands in CommandUsage against
+// store, reporting what it did to out
+func RunCommand(ctx context.Context, store PlayerStore, out io.Writer, args []string) error {
+	if len(args) == 0 {
+		return ErrUnknownCommand
+	}
+	command, args := args[0], args[1:]
+
+	switch command {
+	case "rename":
+		editor, err := playerEditor(store)
+		if err != nil {
+			return err
+		}
+		if len(args) != 2 {
+			return fmt.Errorf("rename takes the player's name and their new name")
+		}
+		if err := editor.RenamePlayer(ctx, args[0], args[1]); err != nil {
+			return fmt.Errorf("problem renaming %s, %w", args[0], err)
+		}
+		fmt.Fprintf(out, "renamed %s to %s\n", args[0], args[1])
+	case "merge":
+		editor, err := playerEditor(store)
+		if err != nil {
+			return err
+		}
+		if len(args) < 2 {
+			return fmt.Errorf("merge takes the player to merge into and at least one player to merge")
+		}
+		if err := editor.MergePlayers(ctx, args[0], args[1:]); err != nil {
+			return fmt.Errorf("problem merging into %s, %w", args[0], err)
+		}
+		fmt.Fprintf(out, "merged %v into %s\n", args[1:], args[0])
+	case "undo":
+		return undo(ctx, store, out, args)
+	case "import":
+		return importLeague(ctx, store, out, args)
+	case "export":
+		return exportLeague(ctx, store, out, args)
+	default:
+		return ErrUnknownCommand
+	}
+	return nil
+}
+
+func undo(ctx context.Context, store PlayerStore, out io.Writer, args []string) error {
+	undoer, ok := store.(UndoStore)
+	if !ok {
+		return errors.New("this store can't undo games")
+	}
+	n := 1
+	if len(args) > 1 {
+		return fmt.Errorf("undo takes at most the number of games to undo")
+	}
+	if len(args) == 1 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil {
+			return fmt.Errorf("undo takes a number of games, %w", ErrInvalidUndo)
+		}
+	}
+
+	undone, err := undoer.UndoGames(ctx, n)
+	if err != nil {
+		return fmt.Errorf("problem undoing games, %w", err)
+	}
+	for _, game := range undone {
+		fmt.Fprintf(out, "undid game %d won by %s\n", game.ID, game.Winner)
+	}
+	return nil
+}
+
+func importLeague(ctx context.Context, store PlayerStore, out io.Writer, args []string) error {
+	dataStore, err := leagueDataStore(store)
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(out)
+	replace := flags.Bool("replace", false, "replace the league's players and games rather than merging into them")
+	dryRun := flags.Bool("dry-run", false, "report what would be imported without importing it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("import takes the file to import")
+	}
+
+	path := flags.Arg(0)
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("problem opening %s, %w", path, err)
+	}
+	defer file.Close()
+	format := FormatJSON
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		format = FormatCSV
+	}
+	data, err := DecodeLeagueData(file, format)
+	if err != nil {
+		return fmt.Errorf("problem reading %s, %w", path, err)
+	}
+
+	mode := ImportMerge
+	if *replace {
+		mode = ImportReplace
+	}
+	report, err := dataStore.ImportLeague(ctx, data, mode, *dryRun)
+	if err != nil {
+		return fmt.Errorf("problem importing %s, %w", path, err)
+	}
+	verb := "imported"
+	if report.DryRun {
+		verb = "would import"
+	}
+	fmt.Fprintf(out, "%s carried over wins for %d players and %d games from %s (%s), skipped %d games already recorded\n",
+		verb, report.Players, report.Games, path, report.Mode, report.Skipped)
+	return nil
+}
+
+func exportLeague(ctx context.Context, store PlayerStore, out io.Writer, args []string) error {
+	dataStore, err := leagueDataStore(store)
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(out)
+	table := flags.String("csv", "", "export the games or carried over wins as CSV rather than everything as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("export takes at most the file to export to")
+	}
+
+	data, err := dataStore.ExportLeague(ctx)
+	if err != nil {
+		return fmt.Errorf("problem exporting league, %w", err)
+	}
+	format := FormatJSON
+	if *table != "" {
+		format = FormatCSV
+	}
+	if flags.NArg() == 0 {
+		return EncodeLeagueData(out, data, format, *table)
+	}
+
+	path := flags.Arg(0)
+	var export bytes.Buffer
+	if err := EncodeLeagueData(&export, data, format, *table); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, export.Bytes(), 0666); err != nil {
+		return fmt.Errorf("problem writing %s, %w", path, err)
+	}
+	fmt.Fprintf(out, "exported carried over wins for %d players and %d games to %s\n", len(data.League), len(data.Games), path)
+	return nil
+}
+
+func leagueDataStore(store PlayerStore) (LeagueDataStore, error) {
+	dataStore, ok := store.(LeagueDataStore)
+	if !ok {
+		return nil, errors.New("this store can't import or export league data")
+	}
+	return dataStore, nil
+}
+
+func playerEditor(store PlayerStore) (PlayerEditor, error) {
+	editor, ok := store.(PlayerEditor)
+	if !ok {
+		return nil, errors.New("this store can't rename or merge players")
+	}
+	return editor, nil
+}
@@ -1,16 +1,32 @@
 package poker
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
-	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
 )
 
+// lockRetryDelay is how often a blocked store retries the file lock
+const lockRetryDelay = 10 * time.Millisecond
+
+// FileSystemPlayerStore is safe for concurrent use, mu guards the in-memory
+// league within the process and an advisory lock on name.lock guards the
+// file across processes, so the CLI and webserver can share one database
 type FileSystemPlayerStore struct {
+	mu       sync.Mutex
 	database *json.Encoder
-	league   League
+	tape     *Tape
+	lock     *flock.Flock
+	loaded   os.FileInfo
+	db       playerDB
 }
 
 func FileSystemPlayerStoreFromFile(path string) (*FileSystemPlayerStore, func(), error) {
@@ -19,72 +35,433 @@ func FileSystemPlayerStoreFromFile(path string) (*FileSystemPlayerStore, func(),
 		return nil, nil, fmt.Errorf("problem opening File %s, %v", path, err)
 	}
 
-	closeFunc := func() {
-		db.Close()
-	}
-
 	store, err := NewFileSystemPlayerStore(db)
 
 	if err != nil {
+		db.Close()
 		return nil, nil, fmt.Errorf("problem creating File system player store, %v", err)
 	}
+
+	// the tape swaps in a new File on every write, it closes those and db
+	// is closed here
+	closeFunc := func() {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		store.tape.Close()
+		db.Close()
+		store.lock.Close()
+	}
 	return store, closeFunc, nil
 }
 
 func NewFileSystemPlayerStore(file *os.File) (*FileSystemPlayerStore, error) {
+	lock := flock.New(file.Name() + ".lock")
+	if err := lock.Lock(); err != nil {
+		return nil, fmt.Errorf("problem locking player db File %s, %v", file.Name(), err)
+	}
+	defer lock.Unlock()
+
 	err := initialisePlayerDBFile(file)
 	if err != nil {
 		return nil, fmt.Errorf("problem initialising player db File, %v", err)
 	}
-	league, err := NewLeague(file)
+	db, restored, err := loadPlayerDB(file)
 	if err != nil {
-		return nil, fmt.Errorf("problem loading player store from File %s, %v", file.Name(), err)
+		return nil, err
 	}
 	// build the encoder and configure indentation
-	enc := json.NewEncoder(&Tape{file})
+	tape := &Tape{File: file, Backups: DefaultBackupGenerations}
+	enc := json.NewEncoder(tape)
 	enc.SetIndent("", "  ") // <-- prettify every Encode call
 
+	if restored {
+		if err := enc.Encode(db); err != nil {
+			return nil, fmt.Errorf("problem restoring File %s from backup, %v", file.Name(), err)
+		}
+	}
+
+	loaded, err := tape.File.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("problem getting File info from File %s, %v", file.Name(), err)
+	}
+
 	return &FileSystemPlayerStore{
 		database: enc,
-		league:   league,
+		tape:     tape,
+		lock:     lock,
+		loaded:   loaded,
+		db:       db,
 	}, nil
 }
 
+// loadPlayerDB reads the database from file, falling back to the newest
+// backup generation that still parses when file is corrupt or was left empty
+func loadPlayerDB(file *os.File) (db playerDB, restored bool, err error) {
+	db, err = decodePlayerDB(file)
+	if err == nil {
+		return db, false, nil
+	}
+
+	for i := 1; i <= DefaultBackupGenerations; i++ {
+		backup, openErr := os.Open(backupPath(file.Name(), i))
+		if openErr != nil {
+			continue
+		}
+		backupDB, backupErr := decodePlayerDB(backup)
+		backup.Close()
+		if backupErr == nil {
+			log.Printf("%s is corrupt (%v), restored from backup generation %d", file.Name(), err, i)
+			return backupDB, true, nil
+		}
+	}
+
+	return playerDB{}, false, fmt.Errorf("problem loading player store from File %s, %v", file.Name(), err)
+}
+
+// GetLeague returns a sorted snapshot, callers are free to modify it
 func (f *FileSystemPlayerStore) GetLeague() League {
-	sort.Slice(f.league, func(i, j int) bool {
-		return f.league[i].Wins > f.league[j].Wins
-	})
-	return f.league
+	league, err := f.GetLeagueContext(context.Background())
+	if err != nil {
+		log.Printf("problem getting League, %v", err)
+	}
+	return league
 }
 
 func (f *FileSystemPlayerStore) GetPlayerScore(name string) int {
-	player := f.league.FindPlayer(name)
-
-	if player != nil {
-		return player.Wins
+	wins, err := f.GetPlayerScoreContext(context.Background(), name)
+	if err != nil && !errors.Is(err, ErrPlayerNotFound) {
+		log.Printf("problem getting score for %s, %v", name, err)
 	}
-
-	return 0
+	return wins
 }
 
 func (f *FileSystemPlayerStore) RecordWin(name string) {
-	player := f.league.FindPlayer(name)
-	if player != nil {
-		player.Wins++
-	} else {
-		f.league = append(f.league, Player{name, 1})
+	if err := f.RecordWinContext(context.Background(), name); err != nil {
+		log.Printf("problem recording win for %s, %v", name, err)
 	}
-	f.database.Encode(f.league)
 }
 
 func (f *FileSystemPlayerStore) DeletePlayer(name string) {
-	for i, p := range f.league {
-		if p.Name == name {
-			f.league[i] = f.league[len(f.league)-1]
-			f.league = f.league[:len(f.league)-1]
+	err := f.DeletePlayerContext(context.Background(), name)
+	if err != nil && !errors.Is(err, ErrPlayerNotFound) {
+		log.Printf("problem deleting player %s, %v", name, err)
+	}
+}
+
+func (f *FileSystemPlayerStore) GetLeagueContext(ctx context.Context) (League, error) {
+	var league League
+	err := f.withFileLock(ctx, false, func() error {
+		var err error
+		league, err = f.db.currentStandings(time.Now())
+		return err
+	})
+	return league, err
+}
+
+func (f *FileSystemPlayerStore) GetPlayerScoreContext(ctx context.Context, name string) (int, error) {
+	var wins int
+	err := f.withFileLock(ctx, false, func() error {
+		season, _ := f.db.activeSeason(time.Now())
+		var found bool
+		if wins, found = f.db.wins(name, season.ID); found {
+			return nil
+		}
+		// known players just haven't won yet this season
+		if f.db.hasPlayer(name) {
+			return nil
+		}
+		return ErrPlayerNotFound
+	})
+	return wins, err
+}
+
+func (f *FileSystemPlayerStore) RecordWinContext(ctx context.Context, name string) error {
+	_, err := f.RecordGame(ctx, GameResult{Winner: name})
+	return err
+}
+
+func (f *FileSystemPlayerStore) DeletePlayerContext(ctx context.Context, name string) error {
+	return f.withFileLock(ctx, true, func() error {
+		db := f.db.clone()
+		if err := db.softDeletePlayer(ctx, name); err != nil {
+			return err
 		}
+		return f.save(db)
+	})
+}
+
+func (f *FileSystemPlayerStore) GetDeleted(ctx context.Context) ([]Tombstone, error) {
+	var deleted []Tombstone
+	err := f.withFileLock(ctx, false, func() error {
+		deleted = f.db.clone().Deleted
+		return nil
+	})
+	return deleted, err
+}
+
+func (f *FileSystemPlayerStore) RestorePlayer(ctx context.Context, name string) error {
+	return f.withFileLock(ctx, true, func() error {
+		db := f.db.clone()
+		if err := db.restorePlayer(ctx, name); err != nil {
+			return err
+		}
+		return f.save(db)
+	})
+}
+
+func (f *FileSystemPlayerStore) PurgePlayer(ctx context.Context, name string) error {
+	return f.withFileLock(ctx, true, func() error {
+		db := f.db.clone()
+		if err := db.purgePlayer(ctx, name); err != nil {
+			return err
+		}
+		return f.save(db)
+	})
+}
+
+func (f *FileSystemPlayerStore) RecordGame(ctx context.Context, game GameResult) (GameResult, error) {
+	game, err := game.normalise()
+	if err != nil {
+		return game, err
 	}
-	f.database.Encode(f.league)
+	err = f.withFileLock(ctx, true, func() error {
+		db := f.db.clone()
+		game = resolveNames(game, db.displayNames())
+		game.ID = db.nextGameID()
+		if game.Time.IsZero() {
+			game.Time = time.Now().UTC()
+		}
+		if season, ok := db.activeSeason(game.Time); ok && game.Season == 0 {
+			game.Season = season.ID
+		}
+		db.addGame(game)
+		return f.save(db)
+	})
+	return game, err
+}
+
+func (f *FileSystemPlayerStore) StartSeason(ctx context.Context, season Season) (Season, error) {
+	err := f.withFileLock(ctx, true, func() error {
+		db := f.db.clone()
+		var err error
+		if season, err = db.startSeason(season); err != nil {
+			return err
+		}
+		return f.save(db)
+	})
+	return season, err
+}
+
+func (f *FileSystemPlayerStore) GetSeasons(ctx context.Context) ([]Season, error) {
+	var seasons []Season
+	err := f.withFileLock(ctx, false, func() error {
+		seasons = f.db.clone().Seasons
+		return nil
+	})
+	return seasons, err
+}
+
+func (f *FileSystemPlayerStore) GetSeasonLeague(ctx context.Context, id int) (League, error) {
+	var league League
+	err := f.withFileLock(ctx, false, func() error {
+		var err error
+		league, err = f.db.seasonStandings(id)
+		return err
+	})
+	return league, err
+}
+
+func (f *FileSystemPlayerStore) GetScoring(ctx context.Context) (string, error) {
+	var scoring string
+	err := f.withFileLock(ctx, false, func() error {
+		scoring = f.db.currentScoring(time.Now())
+		return nil
+	})
+	return scoring, err
+}
+
+func (f *FileSystemPlayerStore) SetScoring(ctx context.Context, name string) error {
+	if _, err := ScoringByName(name); err != nil {
+		return err
+	}
+	return f.withFileLock(ctx, true, func() error {
+		db := f.db.clone()
+		db.Scoring = name
+		return f.save(db)
+	})
+}
+
+func (f *FileSystemPlayerStore) GetPoints(ctx context.Context) (map[string]int, error) {
+	var points map[string]int
+	err := f.withFileLock(ctx, false, func() error {
+		var err error
+		points, err = f.db.currentPoints(time.Now())
+		return err
+	})
+	return points, err
+}
+
+func (f *FileSystemPlayerStore) RenamePlayer(ctx context.Context, from, to string) error {
+	return f.withFileLock(ctx, true, func() error {
+		db := f.db.clone()
+		if _, err := db.renamePlayer(ctx, from, to); err != nil {
+			return err
+		}
+		return f.save(db)
+	})
+}
+
+func (f *FileSystemPlayerStore) MergePlayers(ctx context.Context, into string, from []string) error {
+	return f.withFileLock(ctx, true, func() error {
+		db := f.db.clone()
+		if _, err := db.mergePlayers(ctx, into, from); err != nil {
+			return err
+		}
+		return f.save(db)
+	})
+}
+
+func (f *FileSystemPlayerStore) GetAuditLog(ctx context.Context) ([]AuditEntry, error) {
+	var audit []AuditEntry
+	err := f.withFileLock(ctx, false, func() error {
+		audit = f.db.clone().Audit
+		return nil
+	})
+	return audit, err
+}
+
+func (f *FileSystemPlayerStore) UndoGames(ctx context.Context, n int) ([]GameResult, error) {
+	var undone []GameResult
+	err := f.withFileLock(ctx, true, func() error {
+		db := f.db.clone()
+		var err error
+		if undone, err = db.undoGames(ctx, n); err != nil {
+			return err
+		}
+		return f.save(db)
+	})
+	return undone, err
+}
+
+func (f *FileSystemPlayerStore) GetUndone(ctx context.Context) ([]Undo, error) {
+	var undone []Undo
+	err := f.withFileLock(ctx, false, func() error {
+		undone = f.db.clone().Undone
+		return nil
+	})
+	return undone, err
+}
+
+func (f *FileSystemPlayerStore) RedoGames(ctx context.Context, id int) ([]GameResult, error) {
+	var redone []GameResult
+	err := f.withFileLock(ctx, true, func() error {
+		db := f.db.clone()
+		var err error
+		if redone, err = db.redoGames(ctx, id); err != nil {
+			return err
+		}
+		return f.save(db)
+	})
+	return redone, err
+}
+
+// ExportLeague returns the carried over wins and every game, a copy callers
+// are free to modify
+func (f *FileSystemPlayerStore) ExportLeague(ctx context.Context) (LeagueData, error) {
+	var data LeagueData
+	err := f.withFileLock(ctx, false, func() error {
+		db := f.db.clone()
+		data = LeagueData{League: db.League, Games: db.Games}
+		return nil
+	})
+	return data, err
+}
+
+func (f *FileSystemPlayerStore) ImportLeague(ctx context.Context, data LeagueData, mode ImportMode, dryRun bool) (ImportReport, error) {
+	var report ImportReport
+	err := f.withFileLock(ctx, true, func() error {
+		db := f.db.clone()
+		var err error
+		if report, err = db.importLeague(ctx, data, mode); err != nil {
+			return err
+		}
+		if report.DryRun = dryRun; dryRun {
+			return nil
+		}
+		return f.save(db)
+	})
+	return report, err
+}
+
+func (f *FileSystemPlayerStore) GetGames(ctx context.Context, filter GameFilter) ([]GameResult, error) {
+	var games []GameResult
+	err := f.withFileLock(ctx, false, func() error {
+		games = FilterGames(f.db.Games, filter)
+		return nil
+	})
+	return games, err
+}
+
+// withFileLock runs fn holding mu and a shared or exclusive lock on the
+// database, after reloading the League if another process has written to it
+func (f *FileSystemPlayerStore) withFileLock(ctx context.Context, exclusive bool, fn func() error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lock := f.lock.TryRLockContext
+	if exclusive {
+		lock = f.lock.TryLockContext
+	}
+	if _, err := lock(ctx, lockRetryDelay); err != nil {
+		return fmt.Errorf("problem locking %s, %w", f.tape.File.Name(), err)
+	}
+	defer f.lock.Unlock()
+
+	if err := f.reloadIfChanged(); err != nil {
+		return fmt.Errorf("problem reloading %s, %w", f.tape.File.Name(), err)
+	}
+	return fn()
+}
+
+// reloadIfChanged rereads the League when the file on disk is no longer the
+// one last loaded or written, every Tape write renames in a new file so
+// comparing file identity is enough to spot another process's write
+func (f *FileSystemPlayerStore) reloadIfChanged() error {
+	path := f.tape.File.Name()
+	current, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if os.SameFile(current, f.loaded) && current.ModTime().Equal(f.loaded.ModTime()) && current.Size() == f.loaded.Size() {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	db, err := decodePlayerDB(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.tape.swap(file)
+	f.db = db
+	f.loaded = current
+	return nil
+}
+
+// save writes db to the file and only then makes it the current database,
+// so a failed write leaves the store as it was
+func (f *FileSystemPlayerStore) save(db playerDB) error {
+	if err := f.database.Encode(db); err != nil {
+		return fmt.Errorf("problem saving League to %s, %w", f.tape.File.Name(), err)
+	}
+	f.db = db
+	if info, err := f.tape.File.Stat(); err == nil {
+		f.loaded = info
+	}
+	return nil
 }
 
 func initialisePlayerDBFile(file *os.File) error {
@@ -96,10 +473,16 @@ func initialisePlayerDBFile(file *os.File) error {
 		return fmt.Errorf("problem getting File info from File %s, %v", file.Name(), err)
 	}
 
-	if info.Size() == 0 {
+	// an empty File with backups next to it was lost mid write rather than new
+	if info.Size() == 0 && !fileExists(backupPath(file.Name(), 1)) {
 		file.Write([]byte("[]"))
 		file.Seek(0, io.SeekStart)
 	}
 
 	return nil
 }
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
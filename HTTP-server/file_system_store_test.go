@@ -2,8 +2,14 @@ package poker_test
 
 import (
 	poker "HTTP-server"
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestFileSystemStore(t *testing.T) {
@@ -72,6 +78,241 @@ func TestFileSystemStore(t *testing.T) {
 		_, err := poker.NewFileSystemPlayerStore(database)
 		assertNoError(t, err)
 	})
+	t.Run("get League returns a snapshot", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, `[
+		{"Name": "Cleo", "Wins": 10},
+		{"Name": "Chris", "Wins": 33}]`)
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		league := store.GetLeague()
+		league[0].Wins = 0
+		store.RecordWin("Cleo")
+
+		assertScoreEquals(t, store.GetPlayerScore("Chris"), 33)
+		assertScoreEquals(t, league[1].Wins, 10)
+	})
+}
+
+func TestFileSystemStoreContext(t *testing.T) {
+	database, cleanDatabase := createTempFile(t, `[
+		{"Name": "Chris", "Wins": 33}]`)
+	defer cleanDatabase()
+	store, err := poker.NewFileSystemPlayerStore(database)
+	assertNoError(t, err)
+
+	t.Run("unknown players are not found", func(t *testing.T) {
+		_, err := store.GetPlayerScoreContext(context.Background(), "Apollo")
+		assertError(t, err, poker.ErrPlayerNotFound)
+		err = store.DeletePlayerContext(context.Background(), "Apollo")
+		assertError(t, err, poker.ErrPlayerNotFound)
+	})
+	t.Run("a cancelled context stops the call", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := store.RecordWinContext(ctx, "Chris")
+		assertError(t, err, context.Canceled)
+		assertScoreEquals(t, store.GetPlayerScore("Chris"), 33)
+	})
+}
+
+func TestFileSystemStoreWinTotals(t *testing.T) {
+	database, cleanDatabase := createTempFile(t, `[
+		{"Name": "Chris", "Wins": 3}]`)
+	defer cleanDatabase()
+	store, err := poker.NewFileSystemPlayerStore(database)
+	assertNoError(t, err)
+	ctx := context.Background()
+
+	store.RecordWin("Chris")
+	store.RecordWin("cleo")
+	store.RecordWin("Cleo")
+	store.RecordWin("Floyd")
+	mustStartSeason(t, store, poker.Season{Name: "Q4", Start: time.Now().Add(-time.Hour)})
+	store.RecordWin("Floyd")
+	assertNoError(t, store.DeletePlayerContext(ctx, "Chris"))
+	assertNoError(t, store.MergePlayers(ctx, "Floyd", []string{"Cleo"}))
+	_, err = store.UndoGames(ctx, 1)
+	assertNoError(t, err)
+	assertNoError(t, store.RestorePlayer(ctx, "Chris"))
+	store.RecordWin("Pepper")
+
+	// the totals kept as the store changed match counting every game again
+	reopened, closeFunc, err := poker.FileSystemPlayerStoreFromFile(database.Name())
+	assertNoError(t, err)
+	defer closeFunc()
+	assertLeague(t, store.GetLeague(), reopened.GetLeague())
+	for _, name := range []string{"Chris", "Cleo", "Floyd", "Pepper"} {
+		assertScoreEquals(t, store.GetPlayerScore(name), reopened.GetPlayerScore(name))
+	}
+	for _, season := range []int{1, 2} {
+		got, gotErr := store.GetSeasonLeague(ctx, season)
+		want, wantErr := reopened.GetSeasonLeague(ctx, season)
+		if gotErr != wantErr {
+			t.Fatalf("got error %v, want %v", gotErr, wantErr)
+		}
+		assertLeague(t, got, want)
+	}
+	assertLeague(t, store.GetLeague(), []poker.Player{{"Pepper", 1}})
+}
+
+func TestFileSystemStoreSharedFile(t *testing.T) {
+	t.Run("sees wins recorded by another store on the same File", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "[]")
+		defer cleanDatabase()
+		cli, closeCLI, err := poker.FileSystemPlayerStoreFromFile(database.Name())
+		assertNoError(t, err)
+		defer closeCLI()
+		web, closeWeb, err := poker.FileSystemPlayerStoreFromFile(database.Name())
+		assertNoError(t, err)
+		defer closeWeb()
+
+		cli.RecordWin("Chris")
+		web.RecordWin("Chris")
+		web.RecordWin("Cleo")
+		cli.DeletePlayer("Cleo")
+
+		assertScoreEquals(t, cli.GetPlayerScore("Chris"), 2)
+		assertLeague(t, web.GetLeague(), []poker.Player{{"Chris", 2}})
+	})
+	t.Run("does not lose writes when both stores record at once", func(t *testing.T) {
+		const winsPerStore = 50
+		database, cleanDatabase := createTempFile(t, "[]")
+		defer cleanDatabase()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			store, closeFunc, err := poker.FileSystemPlayerStoreFromFile(database.Name())
+			assertNoError(t, err)
+			defer closeFunc()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < winsPerStore; j++ {
+					store.RecordWin("Pepper")
+				}
+			}()
+		}
+		wg.Wait()
+
+		store, closeFunc, err := poker.FileSystemPlayerStoreFromFile(database.Name())
+		assertNoError(t, err)
+		defer closeFunc()
+		assertScoreEquals(t, store.GetPlayerScore("Pepper"), 2*winsPerStore)
+	})
+}
+
+func TestFileSystemStoreCrashRecovery(t *testing.T) {
+	t.Run("an interrupted write falls back to the last good backup", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "[]")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+		store.RecordWin("Chris")
+		store.RecordWin("Chris")
+
+		// simulate the old truncate-then-write tape dying half way through
+		path := database.Name()
+		os.WriteFile(path, []byte(`[{"Name": "Chr`), 0666)
+
+		reopened, closeFunc, err := poker.FileSystemPlayerStoreFromFile(path)
+		assertNoError(t, err)
+		defer closeFunc()
+		assertScoreEquals(t, reopened.GetPlayerScore("Chris"), 1)
+
+		os.Remove(path + ".1")
+		os.Remove(path + ".2")
+		repaired, closeRepaired, err := poker.FileSystemPlayerStoreFromFile(path)
+		assertNoError(t, err)
+		defer closeRepaired()
+		assertScoreEquals(t, repaired.GetPlayerScore("Chris"), 1)
+	})
+	t.Run("an emptied File with backups is restored rather than reset", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "[]")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+		store.RecordWin("Chris")
+		store.RecordWin("Cleo")
+
+		os.Truncate(database.Name(), 0)
+
+		reopened, closeFunc, err := poker.FileSystemPlayerStoreFromFile(database.Name())
+		assertNoError(t, err)
+		defer closeFunc()
+		assertLeague(t, reopened.GetLeague(), []poker.Player{{"Chris", 1}})
+	})
+	t.Run("a corrupt File without backups is an error", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, `[{"Name": "Chr`)
+		defer cleanDatabase()
+		_, err := poker.NewFileSystemPlayerStore(database)
+		if err == nil {
+			t.Fatal("expected an error but didn't get one")
+		}
+	})
+}
+
+func TestFileSystemStoreConcurrency(t *testing.T) {
+	const workers = 50
+	const winsPerWorker = 20
+
+	t.Run("concurrent wins are all recorded", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < winsPerWorker; j++ {
+					store.RecordWin("Pepper")
+					store.GetLeague()
+					store.GetPlayerScore("Pepper")
+				}
+			}()
+		}
+		wg.Wait()
+
+		assertScoreEquals(t, store.GetPlayerScore("Pepper"), workers*winsPerWorker)
+	})
+
+	t.Run("concurrent wins, deletes and reads", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				victim := fmt.Sprintf("player-%d", i)
+				for j := 0; j < winsPerWorker; j++ {
+					store.RecordWin("Pepper")
+					store.RecordWin(victim)
+					for _, p := range store.GetLeague() {
+						_ = p.Wins
+					}
+					store.DeletePlayer(victim)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		assertScoreEquals(t, store.GetPlayerScore("Pepper"), workers*winsPerWorker)
+		assertLeague(t, store.GetLeague(), []poker.Player{{"Pepper", workers * winsPerWorker}})
+
+		persisted, closeFunc, err := poker.FileSystemPlayerStoreFromFile(database.Name())
+		assertNoError(t, err)
+		defer closeFunc()
+		assertLeague(t, persisted.GetLeague(), []poker.Player{{"Pepper", workers * winsPerWorker}})
+	})
 }
 
 func createTempFile(t testing.TB, initialData string) (*os.File, func()) {
@@ -84,6 +325,10 @@ func createTempFile(t testing.TB, initialData string) (*os.File, func()) {
 	removeFile := func() {
 		tmpfile.Close()
 		os.Remove(tmpfile.Name())
+		backups, _ := filepath.Glob(tmpfile.Name() + ".*")
+		for _, backup := range backups {
+			os.Remove(backup)
+		}
 	}
 	return tmpfile, removeFile
 }
@@ -94,6 +339,13 @@ func assertScoreEquals(t testing.TB, got, want int) {
 		t.Errorf("got %d, want %d", got, want)
 	}
 }
+func assertError(t testing.TB, got, want error) {
+	t.Helper()
+	if !errors.Is(got, want) {
+		t.Errorf("got error %v, want %v", got, want)
+	}
+}
+
 func assertNoError(t testing.TB, err error) {
 	t.Helper()
 	if err != nil {
@@ -0,0 +1,130 @@
+package poker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidGame is returned when recording a game whose winner and finishing order disagree
+var ErrInvalidGame = errors.New("game needs a winner who finished first and no player placed twice")
+
+// GameSource is where a game was played and its result recorded from
+type GameSource string
+
+const (
+	SourceCLI GameSource = "cli"
+	SourceWeb GameSource = "web"
+	SourceAPI GameSource = "api"
+	// SourceImport is a game imported without saying where it was played
+	SourceImport GameSource = "import"
+)
+
+// GameResult is one finished game
+type GameResult struct {
+	ID      int        `json:"id"`
+	Time    time.Time  `json:"time"`
+	Winner  string     `json:"winner"`
+	Players int        `json:"players,omitempty"`
+	Source  GameSource `json:"source,omitempty"`
+	// Season is the ID of the season the game was played in, if any
+	Season int `json:"season,omitempty"`
+	// Finishing lists the players in the order they finished, winner first,
+	// it's empty when only the winner is known
+	Finishing []string `json:"finishing,omitempty"`
+}
+
+// normalise cleans up every name, fills in the winner and number of players
+// from the finishing order and checks they agree
+func (g GameResult) normalise() (GameResult, error) {
+	g.Winner = CleanPlayerName(g.Winner)
+	if len(g.Finishing) == 0 {
+		if g.Winner == "" {
+			return g, ErrInvalidGame
+		}
+		return g, nil
+	}
+
+	finishing := make([]string, len(g.Finishing))
+	seen := map[string]bool{}
+	for i, name := range g.Finishing {
+		finishing[i] = CleanPlayerName(name)
+		key := PlayerKey(name)
+		if finishing[i] == "" || seen[key] {
+			return g, ErrInvalidGame
+		}
+		seen[key] = true
+	}
+	g.Finishing = finishing
+
+	if g.Winner == "" {
+		g.Winner = g.Finishing[0]
+	}
+	if g.Players == 0 {
+		g.Players = len(g.Finishing)
+	}
+	if !samePlayer(g.Winner, g.Finishing[0]) || g.Players < len(g.Finishing) {
+		return g, ErrInvalidGame
+	}
+	g.Winner = g.Finishing[0]
+	return g, nil
+}
+
+// GameStore is implemented by stores that keep every game played rather
+// than only counting wins, their League totals are derived from the games
+type GameStore interface {
+	// RecordGame stores game, filling in its ID and, when zero, its Time
+	RecordGame(ctx context.Context, game GameResult) (GameResult, error)
+	// GetGames returns the games matching filter, oldest first
+	GetGames(ctx context.Context, filter GameFilter) ([]GameResult, error)
+}
+
+// GameFilter picks games out of the history, zero fields match everything
+type GameFilter struct {
+	// Player matches games the player won or finished in
+	Player string
+	Season int
+	// From is inclusive and To is exclusive
+	From time.Time
+	To   time.Time
+}
+
+func (f GameFilter) Matches(game GameResult) bool {
+	if f.Player != "" && !game.played(f.Player) {
+		return false
+	}
+	if f.Season != 0 && game.Season != f.Season {
+		return false
+	}
+	if !f.From.IsZero() && game.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !game.Time.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// played says whether name won the game or finished in it
+func (g GameResult) played(name string) bool {
+	if samePlayer(g.Winner, name) {
+		return true
+	}
+	for _, player := range g.Finishing {
+		if samePlayer(player, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterGames returns the games in games that match filter
+func FilterGames(games []GameResult, filter GameFilter) []GameResult {
+	matching := []GameResult{}
+	for _, game := range games {
+		if filter.Matches(game) {
+			matching = append(matching, game)
+		}
+	}
+	return matching
+}
@@ -0,0 +1,253 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	friday   = time.Date(2026, time.October, 9, 21, 0, 0, 0, time.UTC)
+	saturday = time.Date(2026, time.October, 10, 1, 0, 0, 0, time.UTC)
+	monday   = time.Date(2026, time.October, 12, 20, 0, 0, 0, time.UTC)
+)
+
+func TestFileSystemStoreGames(t *testing.T) {
+	t.Run("league totals carried over wins and recorded games", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, `[
+		{"Name": "Cleo", "Wins": 10},
+		{"Name": "Chris", "Wins": 33}]`)
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		ctx := context.Background()
+		mustRecordGame(t, store, poker.GameResult{Winner: "Cleo", Time: friday, Players: 5, Source: poker.SourceCLI})
+		mustRecordGame(t, store, poker.GameResult{Winner: "Pepper", Time: monday, Source: poker.SourceWeb})
+
+		assertLeague(t, store.GetLeague(), []poker.Player{{"Chris", 33}, {"Cleo", 11}, {"Pepper", 1}})
+
+		games, err := store.GetGames(ctx, poker.GameFilter{})
+		assertNoError(t, err)
+		assertGames(t, games, []poker.GameResult{
+			{ID: 1, Time: friday, Winner: "Cleo", Players: 5, Source: poker.SourceCLI},
+			{ID: 2, Time: monday, Winner: "Pepper", Source: poker.SourceWeb},
+		})
+	})
+	t.Run("games survive reopening and deleting a player drops their games", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+		mustRecordGame(t, store, poker.GameResult{Winner: "Cleo", Time: friday})
+		mustRecordGame(t, store, poker.GameResult{Winner: "Chris", Time: monday})
+		store.DeletePlayer("Chris")
+
+		reopened, closeFunc, err := poker.FileSystemPlayerStoreFromFile(database.Name())
+		assertNoError(t, err)
+		defer closeFunc()
+		games, err := reopened.GetGames(context.Background(), poker.GameFilter{})
+		assertNoError(t, err)
+		assertGames(t, games, []poker.GameResult{{ID: 1, Time: friday, Winner: "Cleo"}})
+	})
+	t.Run("record win stamps the game with the current time", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		before := time.Now()
+		store.RecordWin("Pepper")
+		games, err := store.GetGames(context.Background(), poker.GameFilter{Player: "Pepper"})
+		assertNoError(t, err)
+		if len(games) != 1 || games[0].Time.Before(before.Add(-time.Second)) {
+			t.Errorf("expected one game stamped with the current time, got %v", games)
+		}
+	})
+}
+
+func TestGameFilter(t *testing.T) {
+	games := []poker.GameResult{
+		{ID: 1, Time: friday, Winner: "Cleo"},
+		{ID: 2, Time: saturday, Winner: "Chris"},
+		{ID: 3, Time: monday, Winner: "Cleo"},
+		{ID: 4, Time: monday, Winner: "Floyd", Finishing: []string{"Floyd", "chris"}},
+	}
+	cases := map[string]struct {
+		filter poker.GameFilter
+		want   []int
+	}{
+		"everything":          {poker.GameFilter{}, []int{1, 2, 3, 4}},
+		"by player":           {poker.GameFilter{Player: "Cleo"}, []int{1, 3}},
+		"by player finishing": {poker.GameFilter{Player: "Chris"}, []int{2, 4}},
+		"from is inclusive":   {poker.GameFilter{From: saturday}, []int{2, 3, 4}},
+		"to is exclusive":     {poker.GameFilter{To: saturday}, []int{1}},
+		"player and range":    {poker.GameFilter{Player: "Cleo", From: saturday}, []int{3}},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := []int{}
+			for _, game := range poker.FilterGames(games, c.filter) {
+				got = append(got, game.ID)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got games %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestGamesEndpoint(t *testing.T) {
+	store := &poker.StubPlayerStore{Games: []poker.GameResult{
+		{ID: 1, Time: friday, Winner: "Cleo", Players: 5, Source: poker.SourceCLI},
+		{ID: 2, Time: saturday, Winner: "Chris", Players: 4, Source: poker.SourceWeb},
+		{ID: 3, Time: monday, Winner: "Cleo", Source: poker.SourceAPI},
+	}}
+	server := mustMakePlayerServer(t, store)
+
+	t.Run("lists every game as JSON", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newGamesRequest(""))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertContentType(t, response, poker.JsonContentType)
+		assertGames(t, getGamesFromResponse(t, response), store.Games)
+	})
+	t.Run("filters by player and date range", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newGamesRequest("?player=Cleo&from=2026-10-09&to=2026-10-09"))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertGames(t, getGamesFromResponse(t, response), store.Games[:1])
+	})
+	t.Run("accepts RFC 3339 times", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newGamesRequest("?from=2026-10-10T00:00:00Z&to=2026-10-12T00:00:00Z"))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertGames(t, getGamesFromResponse(t, response), store.Games[1:2])
+	})
+	t.Run("returns 400 on a bad date", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newGamesRequest("?from=last-friday"))
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+	t.Run("returns 501 when the store keeps no history", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &legacyPlayerStore{scores: map[string]int{}})
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newGamesRequest(""))
+		assertStatus(t, response.Code, http.StatusNotImplemented)
+	})
+}
+
+func TestRecordingGameSources(t *testing.T) {
+	t.Run("POST records an api game with the number of players", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := mustMakePlayerServer(t, store)
+		request, _ := http.NewRequest(http.MethodPost, "/players/Pepper?players=6", nil)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusAccepted)
+		assertGameRecorded(t, store, "Pepper", 6, poker.SourceAPI)
+	})
+	t.Run("POST returns 400 on a bad number of players", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &poker.StubPlayerStore{})
+		request, _ := http.NewRequest(http.MethodPost, "/players/Pepper?players=lots", nil)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+	t.Run("a finished CLI game records the number of players", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		game := poker.NewPokerGame(dummyBlindAlerter, store)
+		game.Start(5)
+		game.Finish("Chris")
+
+		assertGameRecorded(t, store, "Chris", 5, poker.SourceCLI)
+	})
+}
+
+func TestRecordingFinishingOrder(t *testing.T) {
+	t.Run("POST /games records a game with its finishing order", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := mustMakePlayerServer(t, store)
+
+		body := `{"finishing": ["Cleo", "Chris", "Floyd"], "players": 5}`
+		request, _ := http.NewRequest(http.MethodPost, "/games", strings.NewReader(body))
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+
+		assertStatus(t, response.Code, http.StatusCreated)
+		got := store.Games[0]
+		if got.Winner != "Cleo" || got.Players != 5 || got.Source != poker.SourceAPI ||
+			!reflect.DeepEqual(got.Finishing, []string{"Cleo", "Chris", "Floyd"}) {
+			t.Errorf("got game %+v", got)
+		}
+	})
+	t.Run("POST /games returns 400 on an invalid game", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &poker.StubPlayerStore{})
+		for _, body := range []string{`{"winner": "Chris", "finishing": ["Cleo", "Chris"]}`, `not json`} {
+			request, _ := http.NewRequest(http.MethodPost, "/games", bytes.NewBufferString(body))
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+			assertStatus(t, response.Code, http.StatusBadRequest)
+		}
+	})
+	t.Run("a JSON array sent over the websocket is the finishing order", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := httptest.NewServer(mustMakePlayerServer(t, store))
+		defer server.Close()
+
+		ws := mustDialWS(t, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws")
+		defer ws.Close()
+		writeWSMessage(t, ws, `["Cleo", "Chris"]`)
+		time.Sleep(10 * time.Millisecond)
+
+		poker.AssertPlayerWin(t, store, "Cleo")
+		assertGameRecorded(t, store, "Cleo", 2, poker.SourceWeb)
+	})
+}
+
+func mustRecordGame(t testing.TB, store poker.GameStore, game poker.GameResult) {
+	t.Helper()
+	if _, err := store.RecordGame(context.Background(), game); err != nil {
+		t.Fatalf("could not record game %v: %v", game, err)
+	}
+}
+
+func newGamesRequest(query string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, "/games"+query, nil)
+	return req
+}
+
+func getGamesFromResponse(t testing.TB, response *httptest.ResponseRecorder) (games []poker.GameResult) {
+	t.Helper()
+	if err := json.NewDecoder(response.Body).Decode(&games); err != nil {
+		t.Fatalf("Unable to parse response from server %q into games, '%v'", response.Body, err)
+	}
+	return
+}
+
+func assertGames(t testing.TB, got, want []poker.GameResult) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got games %+v, want %+v", got, want)
+	}
+}
+
+func assertGameRecorded(t testing.TB, store *poker.StubPlayerStore, winner string, players int, source poker.GameSource) {
+	t.Helper()
+	games, _ := store.GetGames(context.Background(), poker.GameFilter{})
+	if len(games) != 1 {
+		t.Fatalf("got %d games recorded, want 1", len(games))
+	}
+	got := games[0]
+	if got.Winner != winner || got.Players != players || got.Source != source {
+		t.Errorf("got game %+v, want won by %s with %d players from %s", got, winner, players, source)
+	}
+}
@@ -2,4 +2,12 @@ module HTTP-server
 
 go 1.24.0
 
-require github.com/gorilla/websocket v1.5.3 // indirect
+require (
+	github.com/gofrs/flock v0.12.1
+	github.com/gorilla/websocket v1.5.3
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.33
+	golang.org/x/text v0.30.0
+)
+
+require golang.org/x/sys v0.22.0 // indirect
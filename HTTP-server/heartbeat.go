@@ -0,0 +1,54 @@
+package poker
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// DefaultHeartbeat is how often a PlayerServer pings its websockets
+	DefaultHeartbeat = 30 * time.Second
+
+	// wsWriteWait is how long a write to a websocket may take before the
+	// client is taken for dead
+	wsWriteWait = 10 * time.Second
+)
+
+// WithHeartbeat sets how often websockets are pinged, a client that misses
+// two pings in a row is disconnected. Zero or less turns pings off.
+func WithHeartbeat(interval time.Duration) ServerOption {
+	return func(p *PlayerServer) {
+		p.heartbeat = interval
+	}
+}
+
+// keepAlive pings c every interval until stop is called. Reads fail once
+// the client hasn't answered a ping for two intervals.
+func (c *wsConn) keepAlive(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	c.SetReadDeadline(time.Now().Add(2 * interval))
+	c.SetPongHandler(func(string) error {
+		return c.SetReadDeadline(time.Now().Add(2 * interval))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := c.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,234 @@
+package poker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader names a request so that retrying it with the same
+	// key replays the first response rather than recording the win again
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// DefaultIdempotencyWindow is how long a PlayerServer remembers a key
+	DefaultIdempotencyWindow = 24 * time.Hour
+	// DefaultIdempotencyLimit is how many keys a PlayerServer remembers at once
+	DefaultIdempotencyLimit = 10000
+
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize is the largest body a request with a key may
+	// have, the body is read in full to tell retries from new requests
+	maxIdempotentBodySize = 1 << 20
+)
+
+// errIdempotencyFull is returned when every key the cache has room for is
+// still waiting on its first response
+var errIdempotencyFull = errors.New("too many requests in progress, try again")
+
+// WithIdempotencyWindow sets how long idempotency keys and game IDs are
+// remembered, zero or less stops them being remembered at all
+func WithIdempotencyWindow(window time.Duration) ServerOption {
+	return func(p *PlayerServer) {
+		p.idempotencyWindow = window
+	}
+}
+
+// WithIdempotencyLimit sets how many idempotency keys and game IDs are
+// remembered at once, the oldest are forgotten early to make room for new
+// ones. Zero or less stops them being remembered at all.
+func WithIdempotencyLimit(keys int) ServerOption {
+	return func(p *PlayerServer) {
+		p.idempotencyLimit = keys
+	}
+}
+
+// idempotencyCache remembers the response to each key it has seen until the
+// window passes, or until limit newer keys push it out. Keys are kept in
+// memory so are forgotten on restart.
+type idempotencyCache struct {
+	mu      sync.Mutex
+	window  time.Duration
+	limit   int
+	entries map[string]*idempotentResponse
+	// expiring holds the finished entries in the order they expire, which
+	// is the order they finished in as every entry is kept for window
+	expiring []expiringKey
+}
+
+type expiringKey struct {
+	key   string
+	entry *idempotentResponse
+}
+
+// idempotentResponse is the response to the first request made with a key,
+// done is closed once it's been written. request is the method, URI and a
+// hash of the body of the request that made it.
+type idempotentResponse struct {
+	request string
+	done    chan struct{}
+	expires time.Time
+	status  int
+	header  http.Header
+	body    []byte
+}
+
+func newIdempotencyCache(window time.Duration, limit int) *idempotencyCache {
+	return &idempotencyCache{window: window, limit: limit, entries: map[string]*idempotentResponse{}}
+}
+
+// begin returns the response remembered for key and false, or a new one to
+// fill in and true when this is the first time key has been seen. It fails
+// with errIdempotencyFull when there's no room to remember key.
+func (c *idempotencyCache) begin(key, request string) (*idempotentResponse, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for len(c.expiring) > 0 && now.After(c.expiring[0].entry.expires) {
+		c.forgetOldest()
+	}
+
+	if entry, ok := c.entries[key]; ok {
+		return entry, false, nil
+	}
+	if len(c.entries) >= c.limit {
+		if len(c.expiring) == 0 {
+			return nil, false, errIdempotencyFull
+		}
+		c.forgetOldest()
+	}
+	entry := &idempotentResponse{request: request, done: make(chan struct{})}
+	c.entries[key] = entry
+	return entry, true, nil
+}
+
+// lookup returns the response remembered for key, whether or not it has
+// been written yet, without starting one
+func (c *idempotencyCache) lookup(key string) (*idempotentResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || (!entry.expires.IsZero() && time.Now().After(entry.expires)) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// forgetOldest forgets the finished entry that expires first
+func (c *idempotencyCache) forgetOldest() {
+	oldest := c.expiring[0]
+	c.expiring[0] = expiringKey{}
+	c.expiring = c.expiring[1:]
+	if c.entries[oldest.key] == oldest.entry {
+		delete(c.entries, oldest.key)
+	}
+}
+
+// finish remembers entry until the window passes, unless the server failed
+// and the request should be tried again
+func (c *idempotencyCache) finish(key string, entry *idempotentResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry.status == 0 || entry.status >= http.StatusInternalServerError {
+		delete(c.entries, key)
+	} else {
+		entry.expires = time.Now().Add(c.window)
+		c.expiring = append(c.expiring, expiringKey{key, entry})
+	}
+	close(entry.done)
+}
+
+// idempotent records the response to POSTs bearing an Idempotency-Key and
+// replays it to any repeat of the request within the window. Reusing a key
+// for a different request, even one differing only in its body, is refused.
+func (p *PlayerServer) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if p.idempotency == nil || key == "" || r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		var body []byte
+		if r.Body != nil {
+			var err error
+			if body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize)); err != nil {
+				http.Error(w, "could not read a request body to check its Idempotency-Key", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		digest := sha256.Sum256(body)
+		request := r.Method + " " + r.URL.RequestURI() + " " + hex.EncodeToString(digest[:])
+		entry, first, err := p.idempotency.begin(key, request)
+		if err != nil {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if !first {
+			if entry.request != request {
+				http.Error(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+				return
+			}
+			select {
+			case <-entry.done:
+				entry.replay(w)
+			case <-r.Context().Done():
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			entry.status, entry.header, entry.body = recorder.status, recorder.Header().Clone(), recorder.body.Bytes()
+			p.idempotency.finish(key, entry)
+		}()
+		next(recorder, r)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+	}
+}
+
+// replay writes the remembered response again
+func (e *idempotentResponse) replay(w http.ResponseWriter) {
+	for name, values := range e.header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(e.status)
+	w.Write(e.body)
+}
+
+// responseRecorder keeps a copy of the response it writes through
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
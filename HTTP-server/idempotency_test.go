@@ -0,0 +1,162 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIdempotentWins(t *testing.T) {
+	withKey := func(request *http.Request, key string) *http.Request {
+		request.Header.Set(poker.IdempotencyKeyHeader, key)
+		return request
+	}
+
+	t.Run("a retried win is only recorded once", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := mustMakePlayerServer(t, store)
+
+		first := serve(server, withKey(newPostWinRequest("Pepper"), "game-1"))
+		retry := serve(server, withKey(newPostWinRequest("Pepper"), "game-1"))
+
+		assertStatus(t, first.Code, http.StatusAccepted)
+		assertStatus(t, retry.Code, http.StatusAccepted)
+		if retry.Header().Get("Idempotent-Replayed") != "true" {
+			t.Error("expected the retry to be marked as replayed")
+		}
+		poker.AssertPlayerWin(t, store, "Pepper")
+	})
+	t.Run("wins without a key or with different keys are all recorded", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := mustMakePlayerServer(t, store)
+
+		serve(server, newPostWinRequest("Pepper"))
+		serve(server, newPostWinRequest("Pepper"))
+		serve(server, withKey(newPostWinRequest("Pepper"), "game-1"))
+		serve(server, withKey(newPostWinRequest("Pepper"), "game-2"))
+
+		if len(store.WinCalls) != 4 {
+			t.Errorf("got %d wins, want 4", len(store.WinCalls))
+		}
+	})
+	t.Run("a retried game replays the game first recorded", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := mustMakePlayerServer(t, store)
+		newGame := func() *http.Request {
+			request := httptest.NewRequest(http.MethodPost, "/games", strings.NewReader(`{"finishing": ["Cleo", "Chris"]}`))
+			return withKey(request, "game-1")
+		}
+
+		first := serve(server, newGame())
+		retry := serve(server, newGame())
+
+		assertStatus(t, retry.Code, http.StatusCreated)
+		assertContentType(t, retry, poker.JsonContentType)
+		assertResponseBody(t, retry.Body.String(), first.Body.String())
+		if len(store.Games) != 1 {
+			t.Errorf("got %d games, want 1", len(store.Games))
+		}
+	})
+	t.Run("a key can't be reused for a different request", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := mustMakePlayerServer(t, store)
+
+		serve(server, withKey(newPostWinRequest("Pepper"), "game-1"))
+		response := serve(server, withKey(newPostWinRequest("Floyd"), "game-1"))
+
+		assertStatus(t, response.Code, http.StatusUnprocessableEntity)
+		poker.AssertPlayerWin(t, store, "Pepper")
+	})
+	t.Run("a key can't be reused for a game with a different body", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := mustMakePlayerServer(t, store)
+		newGame := func(winner string) *http.Request {
+			request := httptest.NewRequest(http.MethodPost, "/games", strings.NewReader(`{"winner": "`+winner+`"}`))
+			return withKey(request, "game-1")
+		}
+
+		assertStatus(t, serve(server, newGame("Cleo")).Code, http.StatusCreated)
+		response := serve(server, newGame("Chris"))
+
+		assertStatus(t, response.Code, http.StatusUnprocessableEntity)
+		if len(store.Games) != 1 || store.Games[0].Winner != "Cleo" {
+			t.Errorf("got games %+v, want only Cleo's", store.Games)
+		}
+	})
+	t.Run("a win the store failed to record can be retried", func(t *testing.T) {
+		store := &poker.StubPlayerStore{Err: errors.New("disk on fire")}
+		server := mustMakePlayerServer(t, store)
+
+		assertStatus(t, serve(server, withKey(newPostWinRequest("Pepper"), "game-1")).Code, http.StatusInternalServerError)
+		store.Err = nil
+		assertStatus(t, serve(server, withKey(newPostWinRequest("Pepper"), "game-1")).Code, http.StatusAccepted)
+		poker.AssertPlayerWin(t, store, "Pepper")
+	})
+	t.Run("keys are forgotten once the window passes", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server, err := poker.NewPlayerServer(store, poker.WithIdempotencyWindow(10*time.Millisecond))
+		assertNoError(t, err)
+
+		serve(server, withKey(newPostWinRequest("Pepper"), "game-1"))
+		time.Sleep(20 * time.Millisecond)
+		serve(server, withKey(newPostWinRequest("Pepper"), "game-1"))
+
+		if want := []string{"Pepper", "Pepper"}; !reflect.DeepEqual(store.WinCalls, want) {
+			t.Errorf("got wins %v, want %v", store.WinCalls, want)
+		}
+	})
+	t.Run("the oldest keys are forgotten to stay under the limit", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server, err := poker.NewPlayerServer(store, poker.WithIdempotencyLimit(2))
+		assertNoError(t, err)
+
+		for _, key := range []string{"game-1", "game-2", "game-3", "game-1", "game-3"} {
+			assertStatus(t, serve(server, withKey(newPostWinRequest("Pepper"), key)).Code, http.StatusAccepted)
+		}
+
+		if want := []string{"Pepper", "Pepper", "Pepper", "Pepper"}; !reflect.DeepEqual(store.WinCalls, want) {
+			t.Errorf("got wins %v, want game-1 recorded again once forgotten", store.WinCalls)
+		}
+	})
+	t.Run("concurrent retries record the win once", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := mustMakePlayerServer(t, store)
+
+		var wg sync.WaitGroup
+		codes := make([]int, 10)
+		for i := range codes {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes[i] = serve(server, withKey(newPostWinRequest("Pepper"), "game-1")).Code
+			}()
+		}
+		wg.Wait()
+
+		for _, code := range codes {
+			assertStatus(t, code, http.StatusAccepted)
+		}
+		poker.AssertPlayerWin(t, store, "Pepper")
+	})
+	t.Run("a game sent again over the websocket is only recorded once", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := httptest.NewServer(mustMakePlayerServer(t, store))
+		defer server.Close()
+		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+		for range 2 {
+			ws := mustDialWS(t, wsURL)
+			writeWSMessage(t, ws, `{"id": "game-1", "winner": "Cleo"}`)
+			time.Sleep(10 * time.Millisecond)
+			ws.Close()
+		}
+
+		poker.AssertPlayerWin(t, store, "Cleo")
+	})
+}
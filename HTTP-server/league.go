@@ -4,19 +4,38 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 )
 
 type League []Player
 
+// FindPlayer returns the player name refers to, matching on PlayerKey. It
+// keys every player it passes, so repeated lookups are better off indexing
+// the League by PlayerKey first.
 func (l League) FindPlayer(name string) *Player {
+	key := PlayerKey(name)
 	for i, p := range l {
-		if p.Name == name {
+		if p.Name == name || PlayerKey(p.Name) == key {
 			return &l[i]
 		}
 	}
 	return nil
 }
 
+func (l League) clone() League {
+	league := make(League, len(l))
+	copy(league, l)
+	return league
+}
+
+// sortByWins orders league by most wins first, keeping ties in their order
+func sortByWins(league League) League {
+	sort.SliceStable(league, func(i, j int) bool {
+		return league[i].Wins > league[j].Wins
+	})
+	return league
+}
+
 func NewLeague(rdr io.Reader) (League, error) {
 	var league []Player
 	err := json.NewDecoder(rdr).Decode(&league)
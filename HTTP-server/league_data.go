@@ -0,0 +1,398 @@
+package poker
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidImport is matched by the ImportError returned for data that
+// can't be imported
+var ErrInvalidImport = errors.New("invalid import")
+
+// ImportError lists everything wrong with data that was to be imported
+type ImportError struct {
+	Problems []string `json:"problems"`
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrInvalidImport, strings.Join(e.Problems, "; "))
+}
+
+func (e *ImportError) Is(target error) bool {
+	return target == ErrInvalidImport
+}
+
+// LeagueData is everything import and export move in and out of a store
+type LeagueData struct {
+	// League holds wins carried over without a game recorded for them
+	League League       `json:"league"`
+	Games  []GameResult `json:"games"`
+}
+
+// DataFormat is how LeagueData is written out
+type DataFormat string
+
+const (
+	FormatJSON DataFormat = "json"
+	FormatCSV  DataFormat = "csv"
+)
+
+// ImportMode says what happens to a store's players and games on import
+type ImportMode string
+
+const (
+	// ImportMerge sets each imported player's carried over wins to the
+	// imported count and adds the imported games to those in the store,
+	// skipping games it already has, so importing an export again changes
+	// nothing
+	ImportMerge ImportMode = "merge"
+	// ImportReplace throws away the store's players and games first, along
+	// with any deleted players and undone games
+	ImportReplace ImportMode = "replace"
+)
+
+// ImportReport says what an import did, or would have done on a dry run
+type ImportReport struct {
+	Mode   ImportMode `json:"mode"`
+	DryRun bool       `json:"dry_run"`
+	// Players is how many players had wins carried over
+	Players int `json:"players"`
+	Games   int `json:"games"`
+	// Skipped counts games the store already had
+	Skipped int `json:"skipped"`
+}
+
+// LeagueDataStore is implemented by stores that can import and export their
+// players and game history in bulk
+type LeagueDataStore interface {
+	ExportLeague(ctx context.Context) (LeagueData, error)
+	// ImportLeague checks all of data before importing any of it, a dry run
+	// reports what would be imported without changing the store
+	ImportLeague(ctx context.Context, data LeagueData, mode ImportMode, dryRun bool) (ImportReport, error)
+}
+
+// ParseImportMode reads mode, defaulting to ImportMerge
+func ParseImportMode(mode string) (ImportMode, error) {
+	switch ImportMode(mode) {
+	case "", ImportMerge:
+		return ImportMerge, nil
+	case ImportReplace:
+		return ImportReplace, nil
+	}
+	return "", fmt.Errorf("%w: mode must be merge or replace", ErrInvalidImport)
+}
+
+// gamesCSVHeader are the columns games are exported as, finishing names the
+// players in the order they finished separated by semicolons
+var gamesCSVHeader = []string{"id", "time", "winner", "players", "source", "finishing"}
+
+// DecodeLeagueData reads data in format. JSON is a LeagueData or, as read by
+// NewLeague, an array of players. CSV has a header row and is either carried
+// over wins, with name and wins columns, or games, with the columns of gamesCSVHeader of
+// which only winner or finishing are needed.
+func DecodeLeagueData(rdr io.Reader, format DataFormat) (LeagueData, error) {
+	switch format {
+	case FormatJSON:
+		return decodeLeagueJSON(rdr)
+	case FormatCSV:
+		return decodeLeagueCSV(rdr)
+	}
+	return LeagueData{}, fmt.Errorf("%w: format must be json or csv", ErrInvalidImport)
+}
+
+func decodeLeagueJSON(rdr io.Reader) (LeagueData, error) {
+	data, err := io.ReadAll(rdr)
+	if err != nil {
+		return LeagueData{}, fmt.Errorf("problem reading import, %w", err)
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		league, err := NewLeague(bytes.NewReader(trimmed))
+		if err != nil {
+			return LeagueData{}, &ImportError{[]string{err.Error()}}
+		}
+		return LeagueData{League: league}, nil
+	}
+
+	var imported LeagueData
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&imported); err != nil {
+		return LeagueData{}, &ImportError{[]string{"error parsing league data: " + err.Error()}}
+	}
+	return imported, nil
+}
+
+func decodeLeagueCSV(rdr io.Reader) (LeagueData, error) {
+	r := csv.NewReader(rdr)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err == io.EOF {
+		return LeagueData{}, nil
+	}
+	if err != nil {
+		return LeagueData{}, &ImportError{[]string{err.Error()}}
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	_, hasWins := columns["wins"]
+	_, hasWinner := columns["winner"]
+	_, hasFinishing := columns["finishing"]
+	switch {
+	case hasWins:
+		return decodeCSVRows(r, columns, []string{"name", "wins"}, playerFromCSV)
+	case hasWinner || hasFinishing:
+		return decodeCSVRows(r, columns, gamesCSVHeader, gameFromCSV)
+	}
+	return LeagueData{}, &ImportError{[]string{"csv header needs name and wins columns for players or a winner or finishing column for games"}}
+}
+
+// decodeCSVRows adds every row to a LeagueData with add, collecting the
+// problems with each row rather than stopping at the first
+func decodeCSVRows(r *csv.Reader, columns map[string]int, known []string, add func(data *LeagueData, get func(string) string) error) (LeagueData, error) {
+	var problems []string
+	for name := range columns {
+		if !slices.Contains(known, name) {
+			problems = append(problems, fmt.Sprintf("unknown column %q", name))
+		}
+	}
+
+	var data LeagueData
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			problems = append(problems, err.Error())
+			if errors.Is(err, csv.ErrFieldCount) {
+				continue
+			}
+			break
+		}
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if err := add(&data, get); err != nil {
+			line, _ := r.FieldPos(0)
+			problems = append(problems, fmt.Sprintf("line %d: %v", line, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return LeagueData{}, &ImportError{problems}
+	}
+	return data, nil
+}
+
+func playerFromCSV(data *LeagueData, get func(string) string) error {
+	wins, err := strconv.Atoi(get("wins"))
+	if err != nil {
+		return fmt.Errorf("wins must be a number")
+	}
+	data.League = append(data.League, Player{get("name"), wins})
+	return nil
+}
+
+func gameFromCSV(data *LeagueData, get func(string) string) error {
+	game := GameResult{Winner: get("winner"), Source: GameSource(get("source"))}
+	if id := get("id"); id != "" {
+		var err error
+		if game.ID, err = strconv.Atoi(id); err != nil {
+			return fmt.Errorf("id must be a number")
+		}
+	}
+	if when := get("time"); when != "" {
+		t, _, err := parseQueryTime(when)
+		if err != nil {
+			return fmt.Errorf("time must be an RFC 3339 time or a date")
+		}
+		game.Time = t
+	}
+	if players := get("players"); players != "" {
+		var err error
+		if game.Players, err = strconv.Atoi(players); err != nil {
+			return fmt.Errorf("players must be a number")
+		}
+	}
+	if finishing := get("finishing"); finishing != "" {
+		game.Finishing = strings.Split(finishing, ";")
+	}
+	data.Games = append(data.Games, game)
+	return nil
+}
+
+// EncodeLeagueData writes data in format, a CSV holds either the games or
+// the wins carried over without a game so table picks which: "games", the
+// default, or "carried". The carried table isn't the league, whose standings
+// count the games as well.
+func EncodeLeagueData(w io.Writer, data LeagueData, format DataFormat, table string) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	case FormatCSV:
+		switch table {
+		case "carried":
+			return writeCSV(w, []string{"name", "wins"}, len(data.League), func(i int) []string {
+				return []string{data.League[i].Name, strconv.Itoa(data.League[i].Wins)}
+			})
+		case "", "games":
+			return writeCSV(w, gamesCSVHeader, len(data.Games), func(i int) []string {
+				game := data.Games[i]
+				return []string{
+					strconv.Itoa(game.ID),
+					game.Time.Format(time.RFC3339Nano),
+					game.Winner,
+					strconv.Itoa(game.Players),
+					string(game.Source),
+					strings.Join(game.Finishing, ";"),
+				}
+			})
+		}
+		return fmt.Errorf("can only export the games or carried wins as csv, not %q", table)
+	}
+	return fmt.Errorf("can only export json or csv, not %q", format)
+}
+
+func writeCSV(w io.Writer, header []string, rows int, row func(i int) []string) error {
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for i := range rows {
+		cw.Write(row(i))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// validate cleans up every name and game in d, returning an ImportError
+// listing every player and game that can't be imported
+func (d LeagueData) validate() (LeagueData, error) {
+	var problems []string
+
+	league := League{}
+	seen := map[string]bool{}
+	for i, player := range d.League {
+		name := CleanPlayerName(player.Name)
+		switch {
+		case name == "":
+			problems = append(problems, fmt.Sprintf("player %d has no name", i+1))
+		case player.Wins < 0:
+			problems = append(problems, fmt.Sprintf("player %s can't have negative wins", name))
+		case seen[PlayerKey(name)]:
+			problems = append(problems, fmt.Sprintf("player %s is listed twice", name))
+		default:
+			seen[PlayerKey(name)] = true
+			league = append(league, Player{name, player.Wins})
+		}
+	}
+
+	// a game without a time can't be told apart from the same game already
+	// in the store, so it could be imported twice
+	games := make([]GameResult, 0, len(d.Games))
+	for i, game := range d.Games {
+		game, err := game.normalise()
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("game %d: %v", i+1, err))
+		case game.Time.IsZero():
+			problems = append(problems, fmt.Sprintf("game %d has no time", i+1))
+		default:
+			games = append(games, game)
+		}
+	}
+
+	if len(problems) > 0 {
+		return LeagueData{}, &ImportError{problems}
+	}
+	return LeagueData{League: league, Games: games}, nil
+}
+
+// importLeague validates data and adds it to the database, or replaces the
+// database's players and games with it. Imported games are given new IDs,
+// in the order they were played, and the season they were played in.
+func (d *playerDB) importLeague(ctx context.Context, data LeagueData, mode ImportMode) (ImportReport, error) {
+	data, err := data.validate()
+	if err != nil {
+		return ImportReport{}, err
+	}
+	report := ImportReport{Mode: mode}
+
+	if mode == ImportReplace {
+		d.League = League{}
+		d.Games = []GameResult{}
+		// deleted players and undone games are thrown away too, restoring
+		// or redoing them would bring the replaced league back
+		d.Deleted = nil
+		d.Undone = nil
+		d.retally()
+	}
+
+	for _, player := range data.League {
+		if existing := d.carriedPlayer(player.Name); existing != nil {
+			existing.Wins = player.Wins
+		} else {
+			d.carryWins(player.Name, player.Wins)
+		}
+		report.Players++
+	}
+
+	// counted so a history holding the same game twice is imported twice,
+	// and skipped twice when imported again
+	existing := map[string]int{}
+	for _, game := range d.Games {
+		existing[gameFingerprint(game)]++
+	}
+	sort.SliceStable(data.Games, func(i, j int) bool { return data.Games[i].Time.Before(data.Games[j].Time) })
+	id := d.nextGameID()
+	for _, game := range data.Games {
+		if fingerprint := gameFingerprint(game); existing[fingerprint] > 0 {
+			existing[fingerprint]--
+			report.Skipped++
+			continue
+		}
+
+		game.ID, game.Season = id, 0
+		id++
+		if season, ok := d.activeSeason(game.Time); ok {
+			game.Season = season.ID
+		}
+		if game.Source == "" {
+			game.Source = SourceImport
+		}
+		d.Games = append(d.Games, game)
+		report.Games++
+	}
+
+	d.canonicalise()
+	d.audit(ctx, AuditImport, nil, string(mode))
+	return report, nil
+}
+
+// gameFingerprint identifies a game by when it was played, to the second,
+// and who played, so importing the same history twice doesn't record it
+// twice even from a CSV written without fractions of a second
+func gameFingerprint(game GameResult) string {
+	names := []string{PlayerKey(game.Winner)}
+	for _, name := range game.Finishing {
+		names = append(names, PlayerKey(name))
+	}
+	return game.Time.UTC().Truncate(time.Second).Format(time.RFC3339) + "|" + strings.Join(names, "|")
+}
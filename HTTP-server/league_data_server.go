@@ -0,0 +1,114 @@
+package poker
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// maxImportSize is the largest body POST /import reads
+const maxImportSize = 32 << 20
+
+// importHandler imports a JSON or CSV body on POST, ?format= says which
+// when the Content-Type doesn't. ?mode=replace, which is admin only, swaps
+// the league for the import rather than merging it in and ?dry_run=true
+// reports what would be imported without importing it.
+func (p *PlayerServer) importHandler(w http.ResponseWriter, r *http.Request) {
+	if p.data == nil {
+		http.Error(w, "this store can't import league data", http.StatusNotImplemented)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	mode, err := ParseImportMode(query.Get("mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if mode == ImportReplace && !p.isAdmin(r) {
+		http.Error(w, "replacing the league is admin only", http.StatusForbidden)
+		return
+	}
+
+	format := DataFormat(query.Get("format"))
+	if format == "" {
+		format = FormatJSON
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = FormatCSV
+		}
+	}
+	data, err := DecodeLeagueData(http.MaxBytesReader(w, r.Body, maxImportSize), format)
+	if err != nil {
+		importError(w, err)
+		return
+	}
+
+	report, err := p.data.ImportLeague(r.Context(), data, mode, query.Get("dry_run") == "true")
+	if err != nil {
+		importError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// exportHandler returns the carried over wins and the games as JSON on GET,
+// or one of them as CSV with ?format=csv and ?data=games or ?data=carried
+func (p *PlayerServer) exportHandler(w http.ResponseWriter, r *http.Request) {
+	if p.data == nil {
+		http.Error(w, "this store can't export league data", http.StatusNotImplemented)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format, table := DataFormat(query.Get("format")), query.Get("data")
+	contentType := JsonContentType
+	switch {
+	case format == "" || format == FormatJSON:
+		format = FormatJSON
+	case format == FormatCSV && (table == "" || table == "games" || table == "carried"):
+		contentType = "text/csv"
+	default:
+		http.Error(w, "format must be json or csv, and data games or carried", http.StatusBadRequest)
+		return
+	}
+
+	data, err := p.data.ExportLeague(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	var export bytes.Buffer
+	if err := EncodeLeagueData(&export, data, format, table); err != nil {
+		storeError(w, err)
+		return
+	}
+	w.Header().Set("content-type", contentType)
+	export.WriteTo(w)
+}
+
+// importError reports everything wrong with an import as a JSON list
+func importError(w http.ResponseWriter, err error) {
+	var invalid *ImportError
+	switch {
+	case errors.As(err, &invalid):
+		writeJSON(w, http.StatusBadRequest, invalid)
+	case errors.Is(err, ErrInvalidImport):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		storeError(w, err)
+	}
+}
@@ -0,0 +1,374 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeLeagueData(t *testing.T) {
+	t.Run("reads a JSON array of players as NewLeague does", func(t *testing.T) {
+		data, err := poker.DecodeLeagueData(strings.NewReader(`[{"Name": "Cleo", "Wins": 10}]`), poker.FormatJSON)
+		assertNoError(t, err)
+		assertLeague(t, data.League, poker.League{{"Cleo", 10}})
+	})
+	t.Run("reads players and games from JSON", func(t *testing.T) {
+		data, err := poker.DecodeLeagueData(strings.NewReader(`{
+			"league": [{"Name": "Cleo", "Wins": 10}],
+			"games": [{"time": "2024-05-01T20:00:00Z", "finishing": ["Chris", "Cleo"]}]
+		}`), poker.FormatJSON)
+		assertNoError(t, err)
+		assertLeague(t, data.League, poker.League{{"Cleo", 10}})
+		if len(data.Games) != 1 || !reflect.DeepEqual(data.Games[0].Finishing, []string{"Chris", "Cleo"}) {
+			t.Errorf("got games %+v", data.Games)
+		}
+	})
+	t.Run("reads players from CSV", func(t *testing.T) {
+		data, err := poker.DecodeLeagueData(strings.NewReader("Name,Wins\nCleo,10\nChris,4\n"), poker.FormatCSV)
+		assertNoError(t, err)
+		assertLeague(t, data.League, poker.League{{"Cleo", 10}, {"Chris", 4}})
+	})
+	t.Run("reads games from CSV", func(t *testing.T) {
+		data, err := poker.DecodeLeagueData(strings.NewReader("time,winner,finishing\n2024-05-01,,Chris;Cleo;Floyd\n2024-05-08T20:00:00Z,Cleo,\n"), poker.FormatCSV)
+		assertNoError(t, err)
+		want := []poker.GameResult{
+			{Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Finishing: []string{"Chris", "Cleo", "Floyd"}},
+			{Time: time.Date(2024, 5, 8, 20, 0, 0, 0, time.UTC), Winner: "Cleo"},
+		}
+		if !reflect.DeepEqual(data.Games, want) {
+			t.Errorf("got games %+v, want %+v", data.Games, want)
+		}
+	})
+	t.Run("reports every problem in a CSV", func(t *testing.T) {
+		_, err := poker.DecodeLeagueData(strings.NewReader("name,wins,colour\nCleo,ten,red\nChris,4,blue\nFloyd,lots,green\n"), poker.FormatCSV)
+		assertError(t, err, poker.ErrInvalidImport)
+		problems := importProblems(t, err)
+		if len(problems) != 3 || !strings.HasPrefix(problems[1], "line 2:") || !strings.HasPrefix(problems[2], "line 4:") {
+			t.Errorf("got problems %q, want the unknown column and lines 2 and 4", problems)
+		}
+	})
+	t.Run("rejects unknown formats, headers and JSON fields", func(t *testing.T) {
+		_, err := poker.DecodeLeagueData(strings.NewReader(""), "xml")
+		assertError(t, err, poker.ErrInvalidImport)
+		_, err = poker.DecodeLeagueData(strings.NewReader("who,when\n"), poker.FormatCSV)
+		assertError(t, err, poker.ErrInvalidImport)
+		_, err = poker.DecodeLeagueData(strings.NewReader(`{"players": []}`), poker.FormatJSON)
+		assertError(t, err, poker.ErrInvalidImport)
+	})
+}
+
+func TestEncodeLeagueData(t *testing.T) {
+	data := poker.LeagueData{
+		League: poker.League{{"Cleo", 10}},
+		Games: []poker.GameResult{
+			{ID: 1, Time: time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC), Winner: "Chris", Players: 3, Source: poker.SourceWeb, Finishing: []string{"Chris", "Cleo"}},
+		},
+	}
+
+	t.Run("the carried over wins and games are written as CSV", func(t *testing.T) {
+		var carried, games bytes.Buffer
+		assertNoError(t, poker.EncodeLeagueData(&carried, data, poker.FormatCSV, "carried"))
+		assertNoError(t, poker.EncodeLeagueData(&games, data, poker.FormatCSV, "games"))
+
+		assertResponseBody(t, carried.String(), "name,wins\nCleo,10\n")
+		assertResponseBody(t, games.String(), "id,time,winner,players,source,finishing\n1,2024-05-01T20:00:00Z,Chris,3,web,Chris;Cleo\n")
+	})
+	t.Run("what's written can be read back", func(t *testing.T) {
+		for _, format := range []poker.DataFormat{poker.FormatJSON, poker.FormatCSV} {
+			var buf bytes.Buffer
+			assertNoError(t, poker.EncodeLeagueData(&buf, data, format, "games"))
+			got, err := poker.DecodeLeagueData(&buf, format)
+			assertNoError(t, err)
+			if !reflect.DeepEqual(got.Games, data.Games) {
+				t.Errorf("%s: got games %+v, want %+v", format, got.Games, data.Games)
+			}
+		}
+	})
+}
+
+func TestFileSystemStoreImport(t *testing.T) {
+	ctx := context.Background()
+	history := poker.LeagueData{
+		League: poker.League{{"cleo", 2}, {"Apollo", 1}},
+		Games: []poker.GameResult{
+			{Time: time.Date(2024, 5, 8, 20, 0, 0, 0, time.UTC), Winner: "Floyd"},
+			{Time: time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC), Finishing: []string{"Cleo", "Floyd"}},
+		},
+	}
+
+	newStore := func(t *testing.T) *poker.FileSystemPlayerStore {
+		return mustMakeFileSystemStore(t, `[{"Name": "Cleo", "Wins": 3}]`)
+	}
+
+	t.Run("merging sets carried over wins and adds games in the order they were played", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Chris")
+
+		report, err := store.ImportLeague(ctx, history, poker.ImportMerge, false)
+		assertNoError(t, err)
+
+		if want := (poker.ImportReport{Mode: poker.ImportMerge, Players: 2, Games: 2}); report != want {
+			t.Errorf("got report %+v, want %+v", report, want)
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Cleo", 3}, {"Apollo", 1}, {"Chris", 1}, {"Floyd", 1}})
+		games, err := store.GetGames(ctx, poker.GameFilter{})
+		assertNoError(t, err)
+		if len(games) != 3 || games[1].ID != 2 || games[1].Winner != "Cleo" || games[1].Source != poker.SourceImport {
+			t.Errorf("got games %+v, want Cleo's imported win second", games)
+		}
+	})
+	t.Run("merging the same games again skips them", func(t *testing.T) {
+		store := newStore(t)
+		_, err := store.ImportLeague(ctx, poker.LeagueData{Games: history.Games}, poker.ImportMerge, false)
+		assertNoError(t, err)
+
+		report, err := store.ImportLeague(ctx, poker.LeagueData{Games: history.Games}, poker.ImportMerge, false)
+		assertNoError(t, err)
+
+		if report.Games != 0 || report.Skipped != 2 {
+			t.Errorf("got report %+v, want both games skipped", report)
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Cleo", 4}, {"Floyd", 1}})
+	})
+	t.Run("merging an export back in changes nothing", func(t *testing.T) {
+		store := newStore(t)
+		_, err := store.ImportLeague(ctx, history, poker.ImportMerge, false)
+		assertNoError(t, err)
+		exported, err := store.ExportLeague(ctx)
+		assertNoError(t, err)
+
+		report, err := store.ImportLeague(ctx, exported, poker.ImportMerge, false)
+		assertNoError(t, err)
+
+		if report.Games != 0 || report.Skipped != 2 {
+			t.Errorf("got report %+v, want both games skipped", report)
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Cleo", 3}, {"Apollo", 1}, {"Floyd", 1}})
+	})
+	t.Run("merging a CSV export of the games back in changes nothing", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Cleo")
+		store.RecordWin("Chris")
+		exported, err := store.ExportLeague(ctx)
+		assertNoError(t, err)
+		var csv bytes.Buffer
+		assertNoError(t, poker.EncodeLeagueData(&csv, exported, poker.FormatCSV, "games"))
+		decoded, err := poker.DecodeLeagueData(&csv, poker.FormatCSV)
+		assertNoError(t, err)
+
+		report, err := store.ImportLeague(ctx, decoded, poker.ImportMerge, false)
+		assertNoError(t, err)
+
+		if report.Games != 0 || report.Skipped != 2 {
+			t.Errorf("got report %+v, want both games skipped", report)
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Cleo", 4}, {"Chris", 1}})
+	})
+	t.Run("replacing throws away the players and games first", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Chris")
+
+		_, err := store.ImportLeague(ctx, history, poker.ImportReplace, false)
+		assertNoError(t, err)
+
+		assertLeague(t, store.GetLeague(), poker.League{{"cleo", 3}, {"Apollo", 1}, {"Floyd", 1}})
+	})
+	t.Run("replacing throws away deleted players and undone games", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Chris")
+		store.RecordWin("Pepper")
+		_, err := store.UndoGames(ctx, 1)
+		assertNoError(t, err)
+		assertNoError(t, store.DeletePlayerContext(ctx, "Chris"))
+
+		_, err = store.ImportLeague(ctx, history, poker.ImportReplace, false)
+		assertNoError(t, err)
+
+		deleted, err := store.GetDeleted(ctx)
+		assertNoError(t, err)
+		undone, err := store.GetUndone(ctx)
+		assertNoError(t, err)
+		if len(deleted) != 0 || len(undone) != 0 {
+			t.Errorf("got deleted %+v and undone %+v, want neither", deleted, undone)
+		}
+		if err := store.RestorePlayer(ctx, "Chris"); err == nil {
+			t.Error("expected Chris couldn't be restored into the new league")
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"cleo", 3}, {"Apollo", 1}, {"Floyd", 1}})
+	})
+	t.Run("a dry run changes nothing", func(t *testing.T) {
+		store := newStore(t)
+
+		report, err := store.ImportLeague(ctx, history, poker.ImportReplace, true)
+		assertNoError(t, err)
+
+		if !report.DryRun || report.Games != 2 {
+			t.Errorf("got report %+v, want a dry run of 2 games", report)
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Cleo", 3}})
+	})
+	t.Run("nothing is imported when anything is invalid", func(t *testing.T) {
+		store := newStore(t)
+		invalid := poker.LeagueData{
+			League: poker.League{{"Chris", 1}, {"chris", 2}, {"", 1}, {"Floyd", -1}},
+			Games:  []poker.GameResult{{Winner: "Chris"}, {Winner: "Floyd", Finishing: []string{"Chris"}}},
+		}
+
+		_, err := store.ImportLeague(ctx, invalid, poker.ImportMerge, false)
+
+		assertError(t, err, poker.ErrInvalidImport)
+		problems := importProblems(t, err)
+		if len(problems) != 5 {
+			t.Errorf("got problems %q, want 5", problems)
+		}
+		if !slices.Contains(problems, "game 1 has no time") {
+			t.Errorf("got problems %q, want the game without a time", problems)
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Cleo", 3}})
+	})
+	t.Run("imports are audited", func(t *testing.T) {
+		store := newStore(t)
+		_, err := store.ImportLeague(ctx, history, poker.ImportReplace, false)
+		assertNoError(t, err)
+
+		audit, err := store.GetAuditLog(ctx)
+		assertNoError(t, err)
+		if len(audit) != 1 || audit[0].Action != poker.AuditImport || audit[0].To != string(poker.ImportReplace) {
+			t.Errorf("got audit %+v, want the replace", audit)
+		}
+	})
+}
+
+func TestImportExportEndpoints(t *testing.T) {
+	newServer := func(t *testing.T) (*poker.PlayerServer, *poker.FileSystemPlayerStore) {
+		t.Helper()
+		return mustMakeFileSystemServer(t, `[{"Name": "Cleo", "Wins": 3}]`)
+	}
+	newImportRequest := func(query, contentType, body string) *http.Request {
+		request := httptest.NewRequest(http.MethodPost, "/import"+query, strings.NewReader(body))
+		request.Header.Set("Content-Type", contentType)
+		return request
+	}
+
+	t.Run("POST /import merges in a CSV", func(t *testing.T) {
+		server, store := newServer(t)
+
+		response := serve(server, newImportRequest("", "text/csv", "name,wins\nChris,4\nCleo,1\n"))
+
+		assertStatus(t, response.Code, http.StatusOK)
+		var report poker.ImportReport
+		assertNoError(t, json.NewDecoder(response.Body).Decode(&report))
+		if report.Players != 2 || report.Mode != poker.ImportMerge {
+			t.Errorf("got report %+v", report)
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Chris", 4}, {"Cleo", 1}})
+	})
+	t.Run("?dry_run=true only reports", func(t *testing.T) {
+		server, store := newServer(t)
+
+		response := serve(server, newImportRequest("?format=csv&dry_run=true", "", "time,winner\n2024-05-01,Chris\n"))
+
+		assertStatus(t, response.Code, http.StatusOK)
+		assertLeague(t, store.GetLeague(), poker.League{{"Cleo", 3}})
+	})
+	t.Run("?mode=replace is admin only", func(t *testing.T) {
+		server, store := newServer(t)
+		body := `{"league": [{"Name": "Chris", "Wins": 1}]}`
+
+		assertStatus(t, serve(server, newImportRequest("?mode=replace", poker.JsonContentType, body)).Code, http.StatusForbidden)
+		assertStatus(t, serve(server, asAdmin(newImportRequest("?mode=replace", poker.JsonContentType, body))).Code, http.StatusOK)
+
+		assertLeague(t, store.GetLeague(), poker.League{{"Chris", 1}})
+	})
+	t.Run("returns every problem with an invalid import", func(t *testing.T) {
+		server, _ := newServer(t)
+
+		response := serve(server, newImportRequest("", "text/csv", "name,wins\nChris,four\n,1\n"))
+
+		assertStatus(t, response.Code, http.StatusBadRequest)
+		var invalid poker.ImportError
+		assertNoError(t, json.NewDecoder(response.Body).Decode(&invalid))
+		if len(invalid.Problems) != 1 {
+			t.Errorf("got problems %q, want the bad wins", invalid.Problems)
+		}
+
+		assertStatus(t, serve(server, newImportRequest("?mode=upsert", "", "[]")).Code, http.StatusBadRequest)
+	})
+	t.Run("GET /export returns JSON or CSV", func(t *testing.T) {
+		server, store := newServer(t)
+		store.RecordWin("Chris")
+
+		response := serve(server, newRequest(http.MethodGet, "/export"))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertContentType(t, response, poker.JsonContentType)
+		var data poker.LeagueData
+		assertNoError(t, json.NewDecoder(response.Body).Decode(&data))
+		if len(data.League) != 1 || len(data.Games) != 1 {
+			t.Errorf("got export %+v, want Cleo's carried wins and Chris's game", data)
+		}
+
+		response = serve(server, newRequest(http.MethodGet, "/export?format=csv&data=carried"))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertContentType(t, response, "text/csv")
+		assertResponseBody(t, response.Body.String(), "name,wins\nCleo,3\n")
+
+		response = serve(server, newRequest(http.MethodGet, "/export?format=csv"))
+		assertStatus(t, response.Code, http.StatusOK)
+		if !strings.HasPrefix(response.Body.String(), "id,time,winner") {
+			t.Errorf("got %q, want the games by default", response.Body.String())
+		}
+
+		for _, table := range []string{"seasons", "league"} {
+			assertStatus(t, serve(server, newRequest(http.MethodGet, "/export?format=csv&data="+table)).Code, http.StatusBadRequest)
+		}
+	})
+	t.Run("returns 501 when the store can't import or export", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &legacyPlayerStore{scores: map[string]int{}})
+		assertStatus(t, serve(server, newImportRequest("", "", "[]")).Code, http.StatusNotImplemented)
+		assertStatus(t, serve(server, newRequest(http.MethodGet, "/export")).Code, http.StatusNotImplemented)
+	})
+}
+
+func TestRunImportExportCommands(t *testing.T) {
+	ctx := context.Background()
+	store := mustMakeFileSystemStore(t, `[{"Name": "Cleo", "Wins": 3}]`)
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "history.csv")
+	assertNoError(t, os.WriteFile(csvFile, []byte("time,finishing\n2024-05-01,Chris;Cleo\n"), 0666))
+
+	out := &bytes.Buffer{}
+	assertNoError(t, poker.RunCommand(ctx, store, out, []string{"import", "-dry-run", csvFile}))
+	assertNoError(t, poker.RunCommand(ctx, store, out, []string{"import", csvFile}))
+	assertResponseBody(t, out.String(),
+		"would import carried over wins for 0 players and 1 games from "+csvFile+" (merge), skipped 0 games already recorded\n"+
+			"imported carried over wins for 0 players and 1 games from "+csvFile+" (merge), skipped 0 games already recorded\n")
+	assertLeague(t, store.GetLeague(), poker.League{{"Cleo", 3}, {"Chris", 1}})
+
+	out.Reset()
+	assertNoError(t, poker.RunCommand(ctx, store, out, []string{"export", "-csv", "carried"}))
+	assertResponseBody(t, out.String(), "name,wins\nCleo,3\n")
+
+	jsonFile := filepath.Join(dir, "league.json")
+	assertNoError(t, poker.RunCommand(ctx, store, out, []string{"export", jsonFile}))
+	assertNoError(t, poker.RunCommand(ctx, store, out, []string{"import", "-replace", jsonFile}))
+	assertLeague(t, store.GetLeague(), poker.League{{"Cleo", 3}, {"Chris", 1}})
+}
+
+func importProblems(t testing.TB, err error) []string {
+	t.Helper()
+	invalid, ok := err.(*poker.ImportError)
+	if !ok {
+		t.Fatalf("got error %v, want an ImportError", err)
+	}
+	return invalid.Problems
+}
@@ -0,0 +1,182 @@
+package poker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// leagueServers serves every named league with a PlayerServer of its own
+type leagueServers struct {
+	mu       sync.Mutex
+	store    LeagueStore
+	fallback http.Handler
+	servers  map[string]*leagueServer
+	options  []ServerOption
+	// isAdmin says whether a request bears the admin token, only an admin
+	// can delete a league
+	isAdmin func(r *http.Request) bool
+}
+
+// leagueServer is the PlayerServer of a league along with how many requests
+// are using it, a league can't be deleted out from under them
+type leagueServer struct {
+	*PlayerServer
+	inFlight int
+}
+
+// leagueRequest is the body of POST /leagues
+type leagueRequest struct {
+	Name string `json:"name"`
+}
+
+// NewLeagueServer serves store as the default league, on the usual routes and
+// under /leagues/default/, alongside the leagues in leagues. /leagues lists
+// and creates leagues, DELETE /leagues/{league} lets an admin remove one no
+// request is using and every route of a PlayerServer is served for each league under
+// /leagues/{league}/.
+func NewLeagueServer(store PlayerStore, leagues LeagueStore, options ...ServerOption) (*PlayerServer, error) {
+	p, err := NewPlayerServer(store, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &leagueServers{
+		store:    leagues,
+		fallback: p.Handler,
+		servers:  map[string]*leagueServer{},
+		options:  options,
+		isAdmin:  p.isAdmin,
+	}
+	router := http.NewServeMux()
+	router.Handle("/leagues", http.HandlerFunc(l.leaguesHandler))
+	router.Handle("/leagues/", http.HandlerFunc(l.leagueHandler))
+	router.Handle("/", p.Handler)
+	p.Handler = router
+
+	return p, nil
+}
+
+// leaguesHandler lists leagues on GET and creates one on POST
+func (l *leagueServers) leaguesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		names, err := l.store.GetLeagues(r.Context())
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, append([]string{DefaultLeague}, names...))
+	case http.MethodPost:
+		var body leagueRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "could not parse league: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if body.Name == DefaultLeague {
+			leagueError(w, ErrLeagueExists)
+			return
+		}
+		if err := l.store.CreateLeague(r.Context(), body.Name); err != nil {
+			leagueError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, body)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// leagueHandler deletes /leagues/{league} for an admin, unless requests are
+// still using it, and hands everything under it to the league's PlayerServer
+func (l *leagueServers) leagueHandler(w http.ResponseWriter, r *http.Request) {
+	name, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/leagues/"), "/")
+
+	if rest == "" {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !l.isAdmin(r) {
+			http.Error(w, "deleting a league is admin only", http.StatusForbidden)
+			return
+		}
+		if name == DefaultLeague {
+			http.Error(w, "the default league can't be deleted", http.StatusConflict)
+			return
+		}
+		if err := l.deleteLeague(r, name); err != nil {
+			leagueError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	server, release, err := l.server(r, name)
+	if err != nil {
+		leagueError(w, err)
+		return
+	}
+	defer release()
+	http.StripPrefix("/leagues/"+name, server).ServeHTTP(w, r)
+}
+
+// deleteLeague deletes league name once nothing is using its store
+func (l *leagueServers) deleteLeague(r *http.Request, name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if server, ok := l.servers[name]; ok && server.inFlight > 0 {
+		return ErrLeagueInUse
+	}
+	delete(l.servers, name)
+	return l.store.DeleteLeague(r.Context(), name)
+}
+
+// server returns the handler for league name, making its PlayerServer the
+// first time the league is used, and a func to call once the request is
+// done with it
+func (l *leagueServers) server(r *http.Request, name string) (http.Handler, func(), error) {
+	if name == DefaultLeague {
+		return l.fallback, func() {}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	server, ok := l.servers[name]
+	if !ok {
+		store, err := l.store.OpenLeague(r.Context(), name)
+		if err != nil {
+			return nil, nil, err
+		}
+		player, err := NewPlayerServer(store, l.options...)
+		if err != nil {
+			return nil, nil, err
+		}
+		server = &leagueServer{PlayerServer: player}
+		l.servers[name] = server
+	}
+
+	server.inFlight++
+	release := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		server.inFlight--
+	}
+	return server, release, nil
+}
+
+func leagueError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrLeagueNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, ErrInvalidLeague):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrLeagueExists), errors.Is(err, ErrLeagueInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		storeError(w, err)
+	}
+}
@@ -0,0 +1,207 @@
+package poker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLeague is the name of the league served by the routes outside /leagues
+const DefaultLeague = "default"
+
+var (
+	// ErrLeagueNotFound is returned when asked for a league that doesn't exist
+	ErrLeagueNotFound = errors.New("league not found")
+	// ErrLeagueExists is returned when creating a league that already exists
+	ErrLeagueExists = errors.New("league already exists")
+	// ErrInvalidLeague is returned for a league name that can't be stored
+	ErrInvalidLeague = errors.New("league names are 1 to 64 letters, digits, - or _")
+	// ErrLeagueInUse is returned when deleting a league requests are still using
+	ErrLeagueInUse = errors.New("league is in use, try again once its requests finish")
+)
+
+var leagueNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// LeagueStore keeps named leagues, each with a PlayerStore of its own so
+// their players, games and seasons never mix
+type LeagueStore interface {
+	CreateLeague(ctx context.Context, name string) error
+	// DeleteLeague removes the league and everything stored in it, closing
+	// its store, so nothing may be using the store any more
+	DeleteLeague(ctx context.Context, name string) error
+	GetLeagues(ctx context.Context) ([]string, error)
+	// OpenLeague returns the store of an existing league
+	OpenLeague(ctx context.Context, name string) (PlayerStore, error)
+}
+
+// OpenStoreFunc opens the PlayerStore kept at path, creating it if needed,
+// along with a func to close it
+type OpenStoreFunc func(path string) (PlayerStore, func(), error)
+
+// DirLeagueStore keeps each league in its own file, named after the league,
+// in a directory. It is safe for concurrent use.
+type DirLeagueStore struct {
+	mu     sync.Mutex
+	dir    string
+	ext    string
+	open   OpenStoreFunc
+	stores map[string]openLeague
+}
+
+type openLeague struct {
+	store PlayerStore
+	close func()
+}
+
+// NewDirLeagueStore keeps leagues in dir as name+ext files opened with open
+func NewDirLeagueStore(dir, ext string, open OpenStoreFunc) (*DirLeagueStore, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, fmt.Errorf("problem creating league directory %s, %v", dir, err)
+	}
+	return &DirLeagueStore{
+		dir:    dir,
+		ext:    ext,
+		open:   open,
+		stores: map[string]openLeague{},
+	}, nil
+}
+
+func (d *DirLeagueStore) CreateLeague(ctx context.Context, name string) error {
+	if err := checkLeagueName(ctx, name); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if fileExists(d.path(name)) {
+		return ErrLeagueExists
+	}
+	_, err := d.openLocked(name)
+	return err
+}
+
+func (d *DirLeagueStore) DeleteLeague(ctx context.Context, name string) error {
+	if err := checkLeagueName(ctx, name); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path := d.path(name)
+	if !fileExists(path) {
+		return ErrLeagueNotFound
+	}
+	if open, ok := d.stores[name]; ok {
+		open.close()
+		delete(d.stores, name)
+	}
+
+	files, err := d.files(path)
+	if err != nil {
+		return fmt.Errorf("problem finding files of league %s, %v", name, err)
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("problem deleting league %s, %v", name, err)
+		}
+	}
+	return nil
+}
+
+// leagueFileSuffix matches what's added to the name of a league's file for
+// the files kept alongside it: its lock, backup generations and journals
+var leagueFileSuffix = regexp.MustCompile(`^(|\.lock|\.[0-9]+|-journal|-wal|-shm)$`)
+
+// files returns the league file at path and the files kept alongside it,
+// leaving alone any other league whose name starts with the same letters
+func (d *DirLeagueStore) files(path string) ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Base(path)
+	var files []string
+	for _, entry := range entries {
+		suffix, ok := strings.CutPrefix(entry.Name(), base)
+		if ok && !entry.IsDir() && leagueFileSuffix.MatchString(suffix) {
+			files = append(files, filepath.Join(d.dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// GetLeagues returns the names of every league, sorted
+func (d *DirLeagueStore) GetLeagues(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("problem reading league directory %s, %v", d.dir, err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), d.ext)
+		if ok && !entry.IsDir() && leagueNamePattern.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (d *DirLeagueStore) OpenLeague(ctx context.Context, name string) (PlayerStore, error) {
+	if err := checkLeagueName(ctx, name); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if open, ok := d.stores[name]; ok {
+		return open.store, nil
+	}
+	if !fileExists(d.path(name)) {
+		return nil, ErrLeagueNotFound
+	}
+	return d.openLocked(name)
+}
+
+// Close closes the store of every league that has been opened
+func (d *DirLeagueStore) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for name, open := range d.stores {
+		open.close()
+		delete(d.stores, name)
+	}
+}
+
+func (d *DirLeagueStore) openLocked(name string) (PlayerStore, error) {
+	store, closeFunc, err := d.open(d.path(name))
+	if err != nil {
+		return nil, fmt.Errorf("problem opening league %s, %v", name, err)
+	}
+	d.stores[name] = openLeague{store, closeFunc}
+	return store, nil
+}
+
+func (d *DirLeagueStore) path(name string) string {
+	return filepath.Join(d.dir, name+d.ext)
+}
+
+func checkLeagueName(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !leagueNamePattern.MatchString(name) {
+		return ErrInvalidLeague
+	}
+	return nil
+}
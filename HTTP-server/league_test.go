@@ -0,0 +1,252 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirLeagueStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("leagues are created, listed and kept in their own files", func(t *testing.T) {
+		dir := t.TempDir()
+		leagues := mustMakeDirLeagueStore(t, dir)
+
+		assertNoError(t, leagues.CreateLeague(ctx, "tuesday"))
+		assertNoError(t, leagues.CreateLeague(ctx, "friday"))
+
+		names, err := leagues.GetLeagues(ctx)
+		assertNoError(t, err)
+		if want := []string{"friday", "tuesday"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("got leagues %v, want %v", names, want)
+		}
+
+		tuesday, err := leagues.OpenLeague(ctx, "tuesday")
+		assertNoError(t, err)
+		friday, err := leagues.OpenLeague(ctx, "friday")
+		assertNoError(t, err)
+		tuesday.RecordWin("Chris")
+		assertLeague(t, tuesday.GetLeague(), poker.League{{"Chris", 1}})
+		assertLeague(t, friday.GetLeague(), poker.League{})
+
+		if _, err := os.Stat(filepath.Join(dir, "tuesday.db.json")); err != nil {
+			t.Errorf("expected the tuesday league in its own file, %v", err)
+		}
+	})
+	t.Run("leagues survive being reopened", func(t *testing.T) {
+		dir := t.TempDir()
+		leagues := mustMakeDirLeagueStore(t, dir)
+		assertNoError(t, leagues.CreateLeague(ctx, "tuesday"))
+		store, err := leagues.OpenLeague(ctx, "tuesday")
+		assertNoError(t, err)
+		store.RecordWin("Cleo")
+		leagues.Close()
+
+		reopened := mustMakeDirLeagueStore(t, dir)
+		store, err = reopened.OpenLeague(ctx, "tuesday")
+		assertNoError(t, err)
+		assertScoreEquals(t, store.GetPlayerScore("Cleo"), 1)
+	})
+	t.Run("deleting a league removes its files", func(t *testing.T) {
+		dir := t.TempDir()
+		leagues := mustMakeDirLeagueStore(t, dir)
+		assertNoError(t, leagues.CreateLeague(ctx, "tuesday"))
+		store, err := leagues.OpenLeague(ctx, "tuesday")
+		assertNoError(t, err)
+		store.RecordWin("Cleo")
+		store.RecordWin("Cleo")
+
+		assertNoError(t, leagues.DeleteLeague(ctx, "tuesday"))
+
+		_, err = leagues.OpenLeague(ctx, "tuesday")
+		assertError(t, err, poker.ErrLeagueNotFound)
+		if files, _ := os.ReadDir(dir); len(files) != 0 {
+			t.Errorf("expected no files left, got %v", files)
+		}
+	})
+	t.Run("deleting a league leaves other leagues' files alone", func(t *testing.T) {
+		dir := t.TempDir()
+		leagues, err := poker.NewDirLeagueStore(dir, "", func(path string) (poker.PlayerStore, func(), error) {
+			return poker.FileSystemPlayerStoreFromFile(path)
+		})
+		assertNoError(t, err)
+		t.Cleanup(leagues.Close)
+		for _, name := range []string{"a", "ab"} {
+			assertNoError(t, leagues.CreateLeague(ctx, name))
+			store, err := leagues.OpenLeague(ctx, name)
+			assertNoError(t, err)
+			store.RecordWin("Cleo")
+			store.RecordWin("Cleo")
+		}
+
+		assertNoError(t, leagues.DeleteLeague(ctx, "a"))
+
+		store, err := leagues.OpenLeague(ctx, "ab")
+		assertNoError(t, err)
+		assertScoreEquals(t, store.GetPlayerScore("Cleo"), 2)
+		if _, err := os.Stat(filepath.Join(dir, "ab.1")); err != nil {
+			t.Errorf("expected league ab's backup kept, %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "a.1")); err == nil {
+			t.Error("expected league a's backup deleted with it")
+		}
+	})
+	t.Run("rejects bad names, duplicates and missing leagues", func(t *testing.T) {
+		leagues := mustMakeDirLeagueStore(t, t.TempDir())
+		assertNoError(t, leagues.CreateLeague(ctx, "tuesday"))
+
+		assertError(t, leagues.CreateLeague(ctx, "tuesday"), poker.ErrLeagueExists)
+		for _, name := range []string{"", "../tuesday", "tues day", strings.Repeat("a", 65)} {
+			assertError(t, leagues.CreateLeague(ctx, name), poker.ErrInvalidLeague)
+		}
+		assertError(t, leagues.DeleteLeague(ctx, "friday"), poker.ErrLeagueNotFound)
+		_, err := leagues.OpenLeague(ctx, "friday")
+		assertError(t, err, poker.ErrLeagueNotFound)
+	})
+}
+
+func TestLeagueServer(t *testing.T) {
+	newServer := func(t *testing.T) (*poker.PlayerServer, *poker.StubPlayerStore) {
+		t.Helper()
+		store := &poker.StubPlayerStore{}
+		server, err := poker.NewLeagueServer(store, mustMakeDirLeagueStore(t, t.TempDir()), poker.WithAdminToken(testAdminToken))
+		assertNoError(t, err)
+		return server, store
+	}
+
+	t.Run("leagues are created, listed and deleted through /leagues", func(t *testing.T) {
+		server, _ := newServer(t)
+
+		response := serve(server, newCreateLeagueRequest("tuesday"))
+		assertStatus(t, response.Code, http.StatusCreated)
+
+		response = serve(server, newRequest(http.MethodGet, "/leagues"))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertResponseBody(t, strings.TrimSpace(response.Body.String()), `["default","tuesday"]`)
+
+		response = serve(server, newRequest(http.MethodDelete, "/leagues/tuesday"))
+		assertStatus(t, response.Code, http.StatusForbidden)
+		response = serve(server, newRequest(http.MethodGet, "/leagues/tuesday/league"))
+		assertStatus(t, response.Code, http.StatusOK)
+
+		response = serve(server, asAdmin(newRequest(http.MethodDelete, "/leagues/tuesday")))
+		assertStatus(t, response.Code, http.StatusOK)
+		response = serve(server, newRequest(http.MethodGet, "/leagues/tuesday/league"))
+		assertStatus(t, response.Code, http.StatusNotFound)
+	})
+	t.Run("each league keeps its own players", func(t *testing.T) {
+		server, store := newServer(t)
+		serve(server, newCreateLeagueRequest("tuesday"))
+		serve(server, newCreateLeagueRequest("friday"))
+
+		assertStatus(t, serve(server, newRequest(http.MethodPost, "/leagues/tuesday/players/Chris")).Code, http.StatusAccepted)
+		serve(server, newRequest(http.MethodPost, "/leagues/tuesday/players/Chris"))
+		serve(server, newRequest(http.MethodPost, "/leagues/friday/players/Cleo"))
+
+		response := serve(server, newRequest(http.MethodGet, "/leagues/tuesday/players/Chris"))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertResponseBody(t, response.Body.String(), "2")
+
+		response = serve(server, newRequest(http.MethodGet, "/leagues/friday/league"))
+		league := getRatedLeagueFromResponse(t, response)
+		if len(league) != 1 || league[0].Name != "Cleo" {
+			t.Errorf("got friday league %v, want only Cleo", league)
+		}
+
+		response = serve(server, newRequest(http.MethodGet, "/leagues/friday/players/Chris"))
+		assertStatus(t, response.Code, http.StatusNotFound)
+		if len(store.WinCalls) != 0 {
+			t.Errorf("expected the default league untouched, got wins %v", store.WinCalls)
+		}
+	})
+	t.Run("the existing routes and /leagues/default are the default league", func(t *testing.T) {
+		server, store := newServer(t)
+
+		serve(server, newPostWinRequest("Pepper"))
+		serve(server, newRequest(http.MethodPost, "/leagues/default/players/Pepper"))
+
+		if want := []string{"Pepper", "Pepper"}; !reflect.DeepEqual(store.WinCalls, want) {
+			t.Errorf("got wins %v in the default league, want %v", store.WinCalls, want)
+		}
+	})
+	t.Run("the websocket is scoped to its league", func(t *testing.T) {
+		leagues := mustMakeDirLeagueStore(t, t.TempDir())
+		server, err := poker.NewLeagueServer(&poker.StubPlayerStore{}, leagues)
+		assertNoError(t, err)
+		httpServer := httptest.NewServer(server)
+		defer httpServer.Close()
+		serve(server, newCreateLeagueRequest("tuesday"))
+
+		ws := mustDialWS(t, "ws"+strings.TrimPrefix(httpServer.URL, "http")+"/leagues/tuesday/ws")
+		defer ws.Close()
+		writeWSMessage(t, ws, "Floyd")
+		time.Sleep(10 * time.Millisecond)
+
+		store, err := leagues.OpenLeague(context.Background(), "tuesday")
+		assertNoError(t, err)
+		assertScoreEquals(t, store.GetPlayerScore("Floyd"), 1)
+	})
+	t.Run("a league can't be deleted while a request is using it", func(t *testing.T) {
+		server, _ := newServer(t)
+		httpServer := httptest.NewServer(server)
+		defer httpServer.Close()
+		serve(server, newCreateLeagueRequest("tuesday"))
+
+		ws := mustDialWS(t, "ws"+strings.TrimPrefix(httpServer.URL, "http")+"/leagues/tuesday/ws")
+		response := serve(server, asAdmin(newRequest(http.MethodDelete, "/leagues/tuesday")))
+		assertStatus(t, response.Code, http.StatusConflict)
+
+		ws.Close()
+		deadline := time.Now().Add(time.Second)
+		for response.Code != http.StatusOK && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			response = serve(server, asAdmin(newRequest(http.MethodDelete, "/leagues/tuesday")))
+		}
+		assertStatus(t, response.Code, http.StatusOK)
+	})
+	t.Run("returns errors for bad league requests", func(t *testing.T) {
+		server, _ := newServer(t)
+		serve(server, newCreateLeagueRequest("tuesday"))
+
+		cases := map[string]struct {
+			request *http.Request
+			want    int
+		}{
+			"duplicate league":       {newCreateLeagueRequest("tuesday"), http.StatusConflict},
+			"creating default":       {newCreateLeagueRequest("default"), http.StatusConflict},
+			"invalid name":           {newCreateLeagueRequest("tues day"), http.StatusBadRequest},
+			"deleting default":       {asAdmin(newRequest(http.MethodDelete, "/leagues/default")), http.StatusConflict},
+			"deleting missing":       {asAdmin(newRequest(http.MethodDelete, "/leagues/friday")), http.StatusNotFound},
+			"deleting as a player":   {newRequest(http.MethodDelete, "/leagues/tuesday"), http.StatusForbidden},
+			"missing league's route": {newRequest(http.MethodGet, "/leagues/friday/league"), http.StatusNotFound},
+		}
+		for name, c := range cases {
+			t.Run(name, func(t *testing.T) {
+				assertStatus(t, serve(server, c.request).Code, c.want)
+			})
+		}
+	})
+}
+
+func mustMakeDirLeagueStore(t testing.TB, dir string) *poker.DirLeagueStore {
+	t.Helper()
+	leagues, err := poker.NewDirLeagueStore(dir, ".db.json", func(path string) (poker.PlayerStore, func(), error) {
+		return poker.FileSystemPlayerStoreFromFile(path)
+	})
+	assertNoError(t, err)
+	t.Cleanup(leagues.Close)
+	return leagues
+}
+
+func newCreateLeagueRequest(name string) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, "/leagues", strings.NewReader(`{"name": "`+name+`"}`))
+	return req
+}
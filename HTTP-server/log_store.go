@@ -0,0 +1,250 @@
+package poker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	logOpWin    = "win"
+	logOpDelete = "delete"
+	logOpSet    = "set"
+
+	// DefaultCompactThreshold is how many records may be appended after the
+	// last snapshot before the log is compacted
+	DefaultCompactThreshold = 1000
+)
+
+// logRecord is one line of the log, set records make up a snapshot
+type logRecord struct {
+	Op   string `json:"op"`
+	Name string `json:"name"`
+	Wins int    `json:"wins,omitempty"`
+}
+
+// LogPlayerStore appends every win and delete to a log file so a write costs
+// the same no matter how big the League is. The League is rebuilt by
+// replaying the log on open, and the log is periodically compacted into a
+// snapshot of set records.
+type LogPlayerStore struct {
+	mu               sync.RWMutex
+	path             string
+	file             *os.File
+	league           League
+	index            map[string]int
+	appended         int
+	CompactThreshold int
+	// failed stops appends once the log may no longer end in a whole record
+	failed error
+}
+
+func LogPlayerStoreFromFile(path string) (*LogPlayerStore, func(), error) {
+	store, err := NewLogPlayerStore(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("problem creating log player store, %v", err)
+	}
+	closeFunc := func() {
+		store.Close()
+	}
+	return store, closeFunc, nil
+}
+
+func NewLogPlayerStore(path string) (*LogPlayerStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("problem opening log %s, %v", path, err)
+	}
+
+	store := &LogPlayerStore{
+		path:             path,
+		file:             file,
+		index:            map[string]int{},
+		CompactThreshold: DefaultCompactThreshold,
+	}
+	if err := store.replay(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("problem replaying log %s, %v", path, err)
+	}
+	return store, nil
+}
+
+// replay applies every record in the log. An unreadable last record was
+// torn by a crash while it was appended, so it's cut off and the log carries
+// on from the record before, anything unreadable before that is an error.
+func (l *LogPlayerStore) replay(file *os.File) error {
+	rdr := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := rdr.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record logRecord
+			if jsonErr := json.Unmarshal(line, &record); jsonErr != nil {
+				if _, peekErr := rdr.Peek(1); peekErr != io.EOF {
+					return fmt.Errorf("bad record %q at offset %d, %v", line, offset, jsonErr)
+				}
+				log.Printf("cutting torn record %q off the end of log %s", line, l.path)
+				return file.Truncate(offset)
+			}
+			if line[len(line)-1] != '\n' {
+				if _, err := file.Write([]byte{'\n'}); err != nil {
+					return err
+				}
+			}
+			l.apply(record)
+			l.appended++
+		}
+		offset += int64(len(line))
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// apply replays record onto the League, index is keyed by PlayerKey and each
+// player keeps the name they were first recorded under
+func (l *LogPlayerStore) apply(record logRecord) {
+	key := PlayerKey(record.Name)
+	switch record.Op {
+	case logOpWin:
+		if i, ok := l.index[key]; ok {
+			l.league[i].Wins++
+			return
+		}
+		l.index[key] = len(l.league)
+		l.league = append(l.league, Player{CleanPlayerName(record.Name), 1})
+	case logOpSet:
+		// a snapshot only sets a key twice when it was written before names
+		// were compared by key, those players are one player now
+		if i, ok := l.index[key]; ok {
+			l.league[i].Wins += record.Wins
+			return
+		}
+		l.index[key] = len(l.league)
+		l.league = append(l.league, Player{CleanPlayerName(record.Name), record.Wins})
+	case logOpDelete:
+		i, ok := l.index[key]
+		if !ok {
+			return
+		}
+		last := len(l.league) - 1
+		l.league[i] = l.league[last]
+		l.index[PlayerKey(l.league[i].Name)] = i
+		l.league = l.league[:last]
+		delete(l.index, key)
+	}
+}
+
+func (l *LogPlayerStore) GetLeague() League {
+	l.mu.RLock()
+	league := make(League, len(l.league))
+	copy(league, l.league)
+	l.mu.RUnlock()
+
+	sort.SliceStable(league, func(i, j int) bool {
+		return league[i].Wins > league[j].Wins
+	})
+	return league
+}
+
+func (l *LogPlayerStore) GetPlayerScore(name string) int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if i, ok := l.index[PlayerKey(name)]; ok {
+		return l.league[i].Wins
+	}
+	return 0
+}
+
+func (l *LogPlayerStore) RecordWin(name string) {
+	l.append(logRecord{Op: logOpWin, Name: name})
+}
+
+func (l *LogPlayerStore) DeletePlayer(name string) {
+	l.append(logRecord{Op: logOpDelete, Name: name})
+}
+
+func (l *LogPlayerStore) append(record logRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.failed != nil {
+		log.Printf("not appending %s record for %s, the log failed earlier, %v", record.Op, record.Name, l.failed)
+		return
+	}
+
+	line, _ := json.Marshal(record)
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		l.failed = fmt.Errorf("problem appending %s record for %s, %v", record.Op, record.Name, err)
+		log.Print(l.failed)
+		return
+	}
+	l.apply(record)
+	l.appended++
+
+	if l.appended >= l.CompactThreshold+len(l.league) {
+		if err := l.compact(); err != nil {
+			log.Printf("problem compacting log, %v", err)
+		}
+	}
+}
+
+// Compact rewrites the log as a snapshot holding one set record per player
+func (l *LogPlayerStore) Compact() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.compact()
+}
+
+func (l *LogPlayerStore) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), ".compact-*")
+	if err != nil {
+		return fmt.Errorf("problem creating snapshot file, %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, p := range l.league {
+		enc.Encode(logRecord{Op: logOpSet, Name: p.Name, Wins: p.Wins})
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("problem writing snapshot, %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("problem syncing snapshot, %v", err)
+	}
+	tmp.Close()
+
+	// the snapshot is opened for appending before it replaces the log, so
+	// that l.file is never left pointing at the log it replaced
+	file, err := os.OpenFile(tmp.Name(), os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("problem opening snapshot, %v", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		file.Close()
+		return fmt.Errorf("problem replacing log with snapshot, %v", err)
+	}
+	l.file.Close()
+	l.file = file
+	l.appended = len(l.league)
+	return nil
+}
+
+func (l *LogPlayerStore) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
@@ -0,0 +1,175 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogStore(t *testing.T) {
+	t.Run("rebuilds the League from the log", func(t *testing.T) {
+		path := createLogFile(t, `{"op":"win","name":"Cleo"}
+{"op":"set","name":"Chris","wins":33}
+{"op":"win","name":"Chris"}
+{"op":"win","name":"Floyd"}
+{"op":"delete","name":"Floyd"}
+`)
+		store := mustMakeLogPlayerStore(t, path)
+
+		got := store.GetLeague()
+		want := poker.League{
+			{"Chris", 34},
+			{"Cleo", 1},
+		}
+		assertLeague(t, got, want)
+	})
+	t.Run("appends a record per win", func(t *testing.T) {
+		path := createLogFile(t, "")
+		store := mustMakeLogPlayerStore(t, path)
+		store.RecordWin("Pepper")
+		store.RecordWin("Pepper")
+		store.DeletePlayer("Pepper")
+		store.RecordWin("Pepper")
+
+		assertScoreEquals(t, store.GetPlayerScore("Pepper"), 1)
+		assertLogLines(t, path, 4)
+	})
+	t.Run("wins survive reopening the log", func(t *testing.T) {
+		path := createLogFile(t, "")
+		store, closeFunc, err := poker.LogPlayerStoreFromFile(path)
+		assertNoError(t, err)
+		store.RecordWin("Pepper")
+		store.RecordWin("Pepper")
+		closeFunc()
+
+		reopened := mustMakeLogPlayerStore(t, path)
+		assertScoreEquals(t, reopened.GetPlayerScore("Pepper"), 2)
+	})
+	t.Run("compacts into a snapshot", func(t *testing.T) {
+		path := createLogFile(t, "")
+		store := mustMakeLogPlayerStore(t, path)
+		store.CompactThreshold = 10
+		for i := 0; i < 25; i++ {
+			store.RecordWin("Pepper")
+			store.RecordWin("Cleo")
+		}
+		store.RecordWin("Cleo")
+		assertNoError(t, store.Compact())
+
+		assertLogLines(t, path, 2)
+		reopened := mustMakeLogPlayerStore(t, path)
+		assertLeague(t, reopened.GetLeague(), poker.League{
+			{"Cleo", 26},
+			{"Pepper", 25},
+		})
+	})
+	t.Run("players told apart by case in an old snapshot become one", func(t *testing.T) {
+		path := createLogFile(t, `{"op":"set","name":"Pepper","wins":3}
+{"op":"set","name":"pepper","wins":2}
+{"op":"win","name":"PEPPER"}
+`)
+		store := mustMakeLogPlayerStore(t, path)
+		assertLeague(t, store.GetLeague(), poker.League{{"Pepper", 6}})
+	})
+	t.Run("returns an error on a corrupt log", func(t *testing.T) {
+		path := createLogFile(t, "not json\n{\"op\":\"win\",\"name\":\"Cleo\"}\n")
+		_, err := poker.NewLogPlayerStore(path)
+		if err == nil {
+			t.Fatal("expected an error but didn't get one")
+		}
+	})
+	t.Run("cuts off a record torn by a crash and carries on", func(t *testing.T) {
+		for _, torn := range []string{`{"op":"win","na`, "not json\n", "\x00\x00\x00"} {
+			path := createLogFile(t, `{"op":"win","name":"Cleo"}`+"\n"+torn)
+			store := mustMakeLogPlayerStore(t, path)
+			store.RecordWin("Chris")
+
+			reopened := mustMakeLogPlayerStore(t, path)
+			assertLeague(t, reopened.GetLeague(), poker.League{{"Cleo", 1}, {"Chris", 1}})
+			assertLogLines(t, path, 2)
+		}
+	})
+	t.Run("finishes a last record missing its newline", func(t *testing.T) {
+		path := createLogFile(t, `{"op":"win","name":"Cleo"}`)
+		store := mustMakeLogPlayerStore(t, path)
+		store.RecordWin("Cleo")
+
+		reopened := mustMakeLogPlayerStore(t, path)
+		assertScoreEquals(t, reopened.GetPlayerScore("Cleo"), 2)
+	})
+	t.Run("keeps appending to the log after compacting", func(t *testing.T) {
+		path := createLogFile(t, "")
+		store := mustMakeLogPlayerStore(t, path)
+		store.RecordWin("Cleo")
+		assertNoError(t, store.Compact())
+		store.RecordWin("Cleo")
+
+		reopened := mustMakeLogPlayerStore(t, path)
+		assertScoreEquals(t, reopened.GetPlayerScore("Cleo"), 2)
+	})
+}
+
+func BenchmarkRecordWin(b *testing.B) {
+	for _, players := range []int{10, 1000} {
+		b.Run(fmt.Sprintf("tape %d players", players), func(b *testing.B) {
+			database, cleanDatabase := createTempFile(b, "[]")
+			defer cleanDatabase()
+			store, err := poker.NewFileSystemPlayerStore(database)
+			assertNoError(b, err)
+			seedPlayers(store, players)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				store.RecordWin("Pepper")
+			}
+		})
+		b.Run(fmt.Sprintf("log %d players", players), func(b *testing.B) {
+			path := createLogFile(b, "")
+			store := mustMakeLogPlayerStore(b, path)
+			seedPlayers(store, players)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				store.RecordWin("Pepper")
+			}
+		})
+	}
+}
+
+func seedPlayers(store poker.PlayerStore, n int) {
+	for i := 0; i < n; i++ {
+		store.RecordWin(fmt.Sprintf("player-%d", i))
+	}
+}
+
+func createLogFile(t testing.TB, initialData string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "game.db.log")
+	if err := os.WriteFile(path, []byte(initialData), 0666); err != nil {
+		t.Fatalf("error creating log file: %v", err)
+	}
+	return path
+}
+
+func mustMakeLogPlayerStore(t testing.TB, path string) *poker.LogPlayerStore {
+	t.Helper()
+	store, closeFunc, err := poker.LogPlayerStoreFromFile(path)
+	if err != nil {
+		t.Fatalf("could not create log player store: %v", err)
+	}
+	t.Cleanup(closeFunc)
+	return store
+}
+
+func assertLogLines(t testing.TB, path string, want int) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	assertNoError(t, err)
+	got := strings.Count(string(data), "\n")
+	if got != want {
+		t.Errorf("got %d records in log, want %d:\n%s", got, want, data)
+	}
+}
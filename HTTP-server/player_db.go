@@ -0,0 +1,554 @@
+package poker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// playerDB is the document FileSystemPlayerStore keeps in its file
+type playerDB struct {
+	// League holds wins carried over from files written before games were
+	// recorded, the Games are counted on top of them
+	League  League       `json:"league"`
+	Games   []GameResult `json:"games"`
+	Seasons []Season     `json:"seasons,omitempty"`
+	// Scoring names the Scoring used for the League outside of seasons with their own
+	Scoring string `json:"scoring,omitempty"`
+	// Audit logs every change made to the players
+	Audit []AuditEntry `json:"audit,omitempty"`
+	// Deleted holds what was removed from deleted players so they can be restored
+	Deleted []Tombstone `json:"deleted,omitempty"`
+	// Undone holds the games taken back by undos so they can be redone
+	Undone []Undo `json:"undone,omitempty"`
+
+	// totals are the running win totals of League and Games, kept so that
+	// reads don't count every game again. They're nil until tallied.
+	totals *winTotals
+}
+
+// winTotals are the wins of a playerDB, all time and in each season, along
+// with its displayNames and where each player's carried over wins are in
+// its League, by PlayerKey
+type winTotals struct {
+	all     *leagueTally
+	seasons map[int]*leagueTally
+	names   map[string]string
+	carried map[string]int
+}
+
+// leagueTally is a League kept up to date as games are won, index finds a
+// player's place in it by PlayerKey
+type leagueTally struct {
+	league League
+	index  map[string]int
+}
+
+func newLeagueTally() *leagueTally {
+	return &leagueTally{index: map[string]int{}}
+}
+
+func (t *leagueTally) win(name string, wins int) {
+	key := PlayerKey(name)
+	if i, ok := t.index[key]; ok {
+		t.league[i].Wins += wins
+		return
+	}
+	t.index[key] = len(t.league)
+	t.league = append(t.league, Player{name, wins})
+}
+
+func (t *leagueTally) clone() *leagueTally {
+	index := make(map[string]int, len(t.index))
+	for key, i := range t.index {
+		index[key] = i
+	}
+	return &leagueTally{league: t.league.clone(), index: index}
+}
+
+func (w *winTotals) name(name string) {
+	if key := PlayerKey(name); w.names[key] == "" {
+		w.names[key] = CleanPlayerName(name)
+	}
+}
+
+func (w *winTotals) add(game GameResult) {
+	w.name(game.Winner)
+	for _, name := range game.Finishing {
+		w.name(name)
+	}
+	w.all.win(game.Winner, 1)
+	if game.Season == 0 {
+		return
+	}
+	season, ok := w.seasons[game.Season]
+	if !ok {
+		season = newLeagueTally()
+		w.seasons[game.Season] = season
+	}
+	season.win(game.Winner, 1)
+}
+
+func (w *winTotals) clone() *winTotals {
+	seasons := make(map[int]*leagueTally, len(w.seasons))
+	for id, season := range w.seasons {
+		seasons[id] = season.clone()
+	}
+	names := make(map[string]string, len(w.names))
+	for key, name := range w.names {
+		names[key] = name
+	}
+	carried := make(map[string]int, len(w.carried))
+	for key, i := range w.carried {
+		carried[key] = i
+	}
+	return &winTotals{all: w.all.clone(), seasons: seasons, names: names, carried: carried}
+}
+
+// decodePlayerDB reads a playerDB, files written before games were recorded
+// hold only a League array and are read as carried over wins
+func decodePlayerDB(rdr io.Reader) (playerDB, error) {
+	data, err := io.ReadAll(rdr)
+	if err != nil {
+		return playerDB{}, fmt.Errorf("error reading player db: %v", err)
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		league, err := NewLeague(bytes.NewReader(trimmed))
+		db := playerDB{League: league}
+		db.canonicalise()
+		return db, err
+	}
+
+	var db playerDB
+	if err := json.Unmarshal(data, &db); err != nil {
+		return playerDB{}, fmt.Errorf("error parsing player db: %v", err)
+	}
+	db.canonicalise()
+	return db, nil
+}
+
+// displayNames maps the PlayerKey of everyone in the database to the name
+// they were first recorded under, the map is the database's own so mustn't
+// be changed
+func (d playerDB) displayNames() map[string]string {
+	if d.totals != nil {
+		return d.totals.names
+	}
+	return d.countNames()
+}
+
+// countNames finds everyone's displayNames from scratch
+func (d playerDB) countNames() map[string]string {
+	names := map[string]string{}
+	add := func(name string) {
+		if key := PlayerKey(name); names[key] == "" {
+			names[key] = CleanPlayerName(name)
+		}
+	}
+	for _, player := range d.League {
+		add(player.Name)
+	}
+	for _, game := range d.Games {
+		add(game.Winner)
+		for _, name := range game.Finishing {
+			add(name)
+		}
+	}
+	return names
+}
+
+// canonicalise rewrites every name to the one its player was first recorded
+// under, merging players written before names were compared by PlayerKey
+// who only differ by case or spacing
+func (d *playerDB) canonicalise() {
+	names := d.countNames()
+
+	league := League{}
+	index := map[string]int{}
+	for _, player := range d.League {
+		key := PlayerKey(player.Name)
+		if i, ok := index[key]; ok {
+			league[i].Wins += player.Wins
+			continue
+		}
+		index[key] = len(league)
+		league = append(league, Player{names[key], player.Wins})
+	}
+	d.League = league
+
+	for i, game := range d.Games {
+		d.Games[i] = resolveNames(game, names)
+	}
+	d.retally()
+}
+
+// retally counts the win totals again from scratch, for changes that
+// rewrite games rather than add one
+func (d *playerDB) retally() {
+	totals := &winTotals{all: newLeagueTally(), seasons: map[int]*leagueTally{}, names: map[string]string{}, carried: map[string]int{}}
+	for i, player := range d.League {
+		totals.name(player.Name)
+		totals.all.win(player.Name, player.Wins)
+		totals.carried[PlayerKey(player.Name)] = i
+	}
+	for _, game := range d.Games {
+		totals.add(game)
+	}
+	d.totals = totals
+}
+
+// carriedPlayer returns the player in League name refers to, or nil when
+// they have no carried over wins
+func (d *playerDB) carriedPlayer(name string) *Player {
+	if d.totals == nil {
+		return d.League.FindPlayer(name)
+	}
+	if i, ok := d.totals.carried[PlayerKey(name)]; ok {
+		return &d.League[i]
+	}
+	return nil
+}
+
+// carryWins adds wins to name's carried over wins, adding them to League
+// when they have none. The win totals are left for retally to count.
+func (d *playerDB) carryWins(name string, wins int) {
+	if player := d.carriedPlayer(name); player != nil {
+		player.Wins += wins
+		return
+	}
+	if d.totals != nil {
+		d.totals.carried[PlayerKey(name)] = len(d.League)
+	}
+	d.League = append(d.League, Player{name, wins})
+}
+
+// addGame records game, which already has its ID, season and names resolved
+func (d *playerDB) addGame(game GameResult) {
+	d.Games = append(d.Games, game)
+	if d.totals != nil {
+		d.totals.add(game)
+	}
+}
+
+// resolveNames rewrites the names in game to the ones in names, keyed by
+// PlayerKey, leaving names it doesn't know alone. A player named twice in
+// the finishing order keeps their best finish.
+func resolveNames(game GameResult, names map[string]string) GameResult {
+	resolve := func(name string) (string, string) {
+		key := PlayerKey(name)
+		if display, ok := names[key]; ok {
+			return display, key
+		}
+		return name, key
+	}
+
+	game.Winner, _ = resolve(game.Winner)
+	if len(game.Finishing) == 0 {
+		return game
+	}
+	finishing := []string{}
+	seen := map[string]bool{}
+	for _, name := range game.Finishing {
+		display, key := resolve(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		finishing = append(finishing, display)
+	}
+	game.Finishing = finishing
+	return game
+}
+
+func (d playerDB) clone() playerDB {
+	games := make([]GameResult, len(d.Games))
+	copy(games, d.Games)
+	seasons := make([]Season, len(d.Seasons))
+	copy(seasons, d.Seasons)
+	audit := make([]AuditEntry, len(d.Audit))
+	copy(audit, d.Audit)
+	deleted := make([]Tombstone, len(d.Deleted))
+	copy(deleted, d.Deleted)
+	undone := make([]Undo, len(d.Undone))
+	copy(undone, d.Undone)
+	clone := playerDB{
+		League:  d.League.clone(),
+		Games:   games,
+		Seasons: seasons,
+		Scoring: d.Scoring,
+		Audit:   audit,
+		Deleted: deleted,
+		Undone:  undone,
+	}
+	if d.totals != nil {
+		clone.totals = d.totals.clone()
+	}
+	return clone
+}
+
+// league totals the carried over wins and every game won
+func (d playerDB) league() League {
+	if d.totals != nil {
+		return d.totals.all.league.clone()
+	}
+	return countWins(d.League.clone(), d.Games, func(GameResult) bool { return true })
+}
+
+// currentLeague is the League of the season running at now, or the all
+// time League when there isn't one
+func (d playerDB) currentLeague(now time.Time) League {
+	if season, ok := d.activeSeason(now); ok {
+		return d.seasonLeague(season.ID)
+	}
+	return d.league()
+}
+
+// chosenScoring is the name of the scoring picked for the season running at
+// now, falling back to the League's, or empty when neither picked one
+func (d playerDB) chosenScoring(now time.Time) string {
+	if season, ok := d.activeSeason(now); ok && season.Scoring != "" {
+		return season.Scoring
+	}
+	return d.Scoring
+}
+
+// currentScoring is the name of the scoring used right now
+func (d playerDB) currentScoring(now time.Time) string {
+	if scoring := d.chosenScoring(now); scoring != "" {
+		return scoring
+	}
+	return DefaultScoring
+}
+
+// currentPoints scores the games of the season running at now, or every
+// game when there isn't one. Carried over wins count as games where only
+// the winner is known.
+func (d playerDB) currentPoints(now time.Time) (map[string]int, error) {
+	scoring, err := ScoringByName(d.currentScoring(now))
+	if err != nil {
+		return nil, err
+	}
+	if season, ok := d.activeSeason(now); ok {
+		return ScorePoints(FilterGames(d.Games, GameFilter{Season: season.ID}), scoring), nil
+	}
+
+	points := ScorePoints(d.Games, scoring)
+	for _, player := range d.League {
+		points[player.Name] += player.Wins * scoring.Score(GameResult{Winner: player.Name})[player.Name]
+	}
+	return points, nil
+}
+
+// currentStandings is the League running at now. It stays ordered by wins
+// then name until a scoring is picked, then goes by that scoring's points.
+func (d playerDB) currentStandings(now time.Time) (League, error) {
+	if d.chosenScoring(now) == "" {
+		return sortByPoints(d.currentLeague(now), nil), nil
+	}
+	points, err := d.currentPoints(now)
+	if err != nil {
+		return nil, err
+	}
+	return sortByPoints(d.currentLeague(now), points), nil
+}
+
+// seasonScoring is the name of the scoring picked for season, falling back
+// to the League's, or empty when neither picked one
+func (d playerDB) seasonScoring(season Season) string {
+	if season.Scoring != "" {
+		return season.Scoring
+	}
+	return d.Scoring
+}
+
+// seasonPoints scores the games of season id with the season's scoring
+func (d playerDB) seasonPoints(id int) (map[string]int, error) {
+	season, ok := d.findSeason(id)
+	if !ok {
+		return nil, ErrSeasonNotFound
+	}
+	scoring, err := ScoringByName(d.seasonScoring(season))
+	if err != nil {
+		return nil, err
+	}
+	return ScorePoints(FilterGames(d.Games, GameFilter{Season: id}), scoring), nil
+}
+
+// seasonStandings is the League of season id, ordered the way
+// currentStandings orders the League running now
+func (d playerDB) seasonStandings(id int) (League, error) {
+	season, ok := d.findSeason(id)
+	if !ok {
+		return nil, ErrSeasonNotFound
+	}
+	if d.seasonScoring(season) == "" {
+		return sortByPoints(d.seasonLeague(id), nil), nil
+	}
+	points, err := d.seasonPoints(id)
+	if err != nil {
+		return nil, err
+	}
+	return sortByPoints(d.seasonLeague(id), points), nil
+}
+
+func (d playerDB) seasonLeague(id int) League {
+	if d.totals != nil {
+		if season, ok := d.totals.seasons[id]; ok {
+			return season.league.clone()
+		}
+		return League{}
+	}
+	return countWins(League{}, d.Games, func(game GameResult) bool { return game.Season == id })
+}
+
+// wins is how many times name has won in season id, or all time when id is
+// zero, found is false when they haven't won at all
+func (d playerDB) wins(name string, id int) (wins int, found bool) {
+	if d.totals == nil {
+		league := d.league()
+		if id != 0 {
+			league = d.seasonLeague(id)
+		}
+		if player := league.FindPlayer(name); player != nil {
+			return player.Wins, true
+		}
+		return 0, false
+	}
+
+	tally := d.totals.all
+	if id != 0 {
+		if tally = d.totals.seasons[id]; tally == nil {
+			return 0, false
+		}
+	}
+	i, ok := tally.index[PlayerKey(name)]
+	if !ok {
+		return 0, false
+	}
+	return tally.league[i].Wins, true
+}
+
+func countWins(league League, games []GameResult, include func(GameResult) bool) League {
+	tally := newLeagueTally()
+	for _, player := range league {
+		tally.win(player.Name, player.Wins)
+	}
+	for _, game := range games {
+		if include(game) {
+			tally.win(game.Winner, 1)
+		}
+	}
+	return tally.league
+}
+
+// hasPlayer reports whether name has ever won, in any season
+func (d playerDB) hasPlayer(name string) bool {
+	_, found := d.wins(name, 0)
+	return found
+}
+
+func (d playerDB) activeSeason(t time.Time) (Season, bool) {
+	for _, season := range d.Seasons {
+		if season.Contains(t) {
+			return season, true
+		}
+	}
+	return Season{}, false
+}
+
+func (d playerDB) findSeason(id int) (Season, bool) {
+	for _, season := range d.Seasons {
+		if season.ID == id {
+			return season, true
+		}
+	}
+	return Season{}, false
+}
+
+// startSeason adds season, closing an open ended season that started before it
+func (d *playerDB) startSeason(season Season) (Season, error) {
+	if season.Name == "" || season.Start.IsZero() || (!season.End.IsZero() && !season.End.After(season.Start)) {
+		return Season{}, ErrInvalidSeason
+	}
+	if _, err := ScoringByName(season.Scoring); err != nil {
+		return Season{}, err
+	}
+
+	seasons := make([]Season, len(d.Seasons))
+	copy(seasons, d.Seasons)
+	season.ID = 1
+	for i, existing := range seasons {
+		if existing.ID >= season.ID {
+			season.ID = existing.ID + 1
+		}
+		if existing.End.IsZero() && existing.Start.Before(season.Start) {
+			seasons[i].End = season.Start
+		}
+	}
+	for _, existing := range seasons {
+		if season.overlaps(existing) {
+			return Season{}, ErrSeasonOverlap
+		}
+	}
+
+	d.Seasons = append(seasons, season)
+	return season, nil
+}
+
+// nextGameID is one more than the highest ID of any game, deleted, undone
+// or not, so a restored or redone game never shares its ID
+func (d playerDB) nextGameID() int {
+	id := 1
+	next := func(games []GameResult) {
+		for _, game := range games {
+			if game.ID >= id {
+				id = game.ID + 1
+			}
+		}
+	}
+	next(d.Games)
+	for _, tombstone := range d.Deleted {
+		next(tombstone.Games)
+	}
+	for _, undo := range d.Undone {
+		next(undo.Games)
+	}
+	return id
+}
+
+// removePlayer takes name's carried over wins and the games they won out of
+// the database and returns them, found reports whether there was anything
+// to remove
+func (d *playerDB) removePlayer(name string) (removed Tombstone, found bool) {
+	league := d.League[:0]
+	for _, p := range d.League {
+		if samePlayer(p.Name, name) {
+			removed.Player = p.Name
+			removed.Wins += p.Wins
+			found = true
+			continue
+		}
+		league = append(league, p)
+	}
+	d.League = league
+
+	games := d.Games[:0]
+	for _, game := range d.Games {
+		if samePlayer(game.Winner, name) {
+			if removed.Player == "" {
+				removed.Player = game.Winner
+			}
+			removed.Games = append(removed.Games, game)
+			found = true
+			continue
+		}
+		games = append(games, game)
+	}
+	d.Games = games
+	d.retally()
+
+	return removed, found
+}
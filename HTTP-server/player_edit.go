@@ -0,0 +1,113 @@
+package poker
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrPlayerExists is returned when renaming a player to a name already in use
+	ErrPlayerExists = errors.New("player already exists, merge the players instead")
+	// ErrInvalidPlayerName is returned when renaming or merging into an empty name
+	ErrInvalidPlayerName = errors.New("player name can't be empty")
+)
+
+// PlayerEditor is implemented by stores that can correct their player
+// records, each change is applied atomically and written to the audit log
+type PlayerEditor interface {
+	// RenamePlayer moves everything recorded against from onto the unused name to
+	RenamePlayer(ctx context.Context, from, to string) error
+	// MergePlayers moves the wins and games of every player in from onto
+	// into, which may be one of them or a new name
+	MergePlayers(ctx context.Context, into string, from []string) error
+}
+
+// renamePlayer checks from exists and to doesn't before merging them
+func (d *playerDB) renamePlayer(ctx context.Context, from, to string) (AuditEntry, error) {
+	to = CleanPlayerName(to)
+	if to == "" {
+		return AuditEntry{}, ErrInvalidPlayerName
+	}
+	if !d.knowsPlayer(from) {
+		return AuditEntry{}, ErrPlayerNotFound
+	}
+	// changing the case or spacing of a name is a rename of the same player
+	if !samePlayer(from, to) && d.knowsPlayer(to) {
+		return AuditEntry{}, ErrPlayerExists
+	}
+	d.mergeInto(to, []string{from})
+	return d.audit(ctx, AuditRename, []string{from}, to), nil
+}
+
+// mergePlayers checks every player in from exists before merging them into into
+func (d *playerDB) mergePlayers(ctx context.Context, into string, from []string) (AuditEntry, error) {
+	into = CleanPlayerName(into)
+	if into == "" || len(from) == 0 {
+		return AuditEntry{}, ErrInvalidPlayerName
+	}
+	for _, name := range from {
+		if !d.knowsPlayer(name) {
+			return AuditEntry{}, ErrPlayerNotFound
+		}
+	}
+	d.mergeInto(into, from)
+	return d.audit(ctx, AuditMerge, from, into), nil
+}
+
+// knowsPlayer reports whether name has carried over wins or played a game
+func (d playerDB) knowsPlayer(name string) bool {
+	_, ok := d.displayNames()[PlayerKey(name)]
+	return ok
+}
+
+// mergeInto sums the carried over wins of from into into and rewrites the
+// games they played, someone merged with a player they played against keeps
+// their best finish
+func (d *playerDB) mergeInto(into string, from []string) {
+	merging := map[string]bool{PlayerKey(into): true}
+	for _, name := range from {
+		merging[PlayerKey(name)] = true
+	}
+
+	league := League{}
+	wins, placed := 0, -1
+	for _, player := range d.League {
+		if !merging[PlayerKey(player.Name)] {
+			league = append(league, player)
+			continue
+		}
+		if placed == -1 {
+			placed = len(league)
+			league = append(league, Player{into, 0})
+		}
+		wins += player.Wins
+	}
+	if placed != -1 {
+		league[placed].Wins = wins
+	}
+	d.League = league
+
+	games := make([]GameResult, len(d.Games))
+	for i, game := range d.Games {
+		if merging[PlayerKey(game.Winner)] {
+			game.Winner = into
+		}
+		if len(game.Finishing) > 0 {
+			finishing := []string{}
+			seen := false
+			for _, name := range game.Finishing {
+				if merging[PlayerKey(name)] {
+					if seen {
+						continue
+					}
+					name, seen = into, true
+				}
+				finishing = append(finishing, name)
+			}
+			game.Finishing = finishing
+		}
+		games[i] = game
+	}
+	d.Games = games
+	d.retally()
+}
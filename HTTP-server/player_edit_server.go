@@ -0,0 +1,57 @@
+package poker
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// renameRequest is the body of PATCH /players/{name}
+type renameRequest struct {
+	Name string `json:"name"`
+}
+
+// mergeRequest is the body of POST /merge
+type mergeRequest struct {
+	Into string   `json:"into"`
+	From []string `json:"from"`
+}
+
+// renamePlayer gives a player the name in the request body
+func (p *PlayerServer) renamePlayer(w http.ResponseWriter, r *http.Request, name string) {
+	if p.editor == nil {
+		http.Error(w, "this store can't rename players", http.StatusNotImplemented)
+		return
+	}
+	var body renameRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "could not parse rename: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := p.editor.RenamePlayer(r.Context(), name, body.Name); err != nil {
+		storeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// mergeHandler merges the players in from into one on POST
+func (p *PlayerServer) mergeHandler(w http.ResponseWriter, r *http.Request) {
+	if p.editor == nil {
+		http.Error(w, "this store can't merge players", http.StatusNotImplemented)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body mergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "could not parse merge: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := p.editor.MergePlayers(r.Context(), body.Into, body.From); err != nil {
+		storeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
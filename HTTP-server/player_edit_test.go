@@ -0,0 +1,187 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileSystemStorePlayerEdits(t *testing.T) {
+	ctx := context.Background()
+
+	newStore := func(t *testing.T) (*poker.FileSystemPlayerStore, string) {
+		database, cleanDatabase := createTempFile(t, `[{"Name": "chris", "Wins": 3}, {"Name": "Cleo", "Wins": 1}]`)
+		t.Cleanup(cleanDatabase)
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+		return store, database.Name()
+	}
+
+	t.Run("rename moves wins and games onto the new name", func(t *testing.T) {
+		store, _ := newStore(t)
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Cleo", "chris"}})
+
+		assertNoError(t, store.RenamePlayer(ctx, "chris", "Chris"))
+
+		assertLeague(t, store.GetLeague(), poker.League{{"Chris", 3}, {"Cleo", 2}})
+		games, err := store.GetGames(ctx, poker.GameFilter{})
+		assertNoError(t, err)
+		if want := []string{"Cleo", "Chris"}; !reflect.DeepEqual(games[0].Finishing, want) {
+			t.Errorf("got finishing order %v, want %v", games[0].Finishing, want)
+		}
+	})
+	t.Run("rename refuses a missing player or a name in use", func(t *testing.T) {
+		store, _ := newStore(t)
+		assertError(t, store.RenamePlayer(ctx, "Apollo", "Chris"), poker.ErrPlayerNotFound)
+		assertError(t, store.RenamePlayer(ctx, "chris", "Cleo"), poker.ErrPlayerExists)
+		assertError(t, store.RenamePlayer(ctx, "chris", ""), poker.ErrInvalidPlayerName)
+	})
+	t.Run("merge sums wins and merges history", func(t *testing.T) {
+		store, path := newStore(t)
+		mustRecordGame(t, store, poker.GameResult{Winner: "Christopher"})
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Cleo", "chris", "Floyd", "Christopher"}})
+
+		assertNoError(t, store.MergePlayers(ctx, "Chris", []string{"chris", "Christopher"}))
+
+		assertLeague(t, store.GetLeague(), poker.League{{"Chris", 4}, {"Cleo", 2}})
+		games, err := store.GetGames(ctx, poker.GameFilter{Player: "Chris"})
+		assertNoError(t, err)
+		if len(games) != 2 || games[0].Winner != "Chris" {
+			t.Errorf("got %+v, want Chris's win and the game Chris finished in", games)
+		}
+		games, err = store.GetGames(ctx, poker.GameFilter{})
+		assertNoError(t, err)
+		if want := []string{"Cleo", "Chris", "Floyd"}; !reflect.DeepEqual(games[1].Finishing, want) {
+			t.Errorf("got finishing order %v, want %v", games[1].Finishing, want)
+		}
+
+		reopened, closeStore, err := poker.FileSystemPlayerStoreFromFile(path)
+		assertNoError(t, err)
+		defer closeStore()
+		assertLeague(t, reopened.GetLeague(), poker.League{{"Chris", 4}, {"Cleo", 2}})
+	})
+	t.Run("a failed merge changes nothing", func(t *testing.T) {
+		store, _ := newStore(t)
+		assertError(t, store.MergePlayers(ctx, "Chris", []string{"chris", "Apollo"}), poker.ErrPlayerNotFound)
+		assertLeague(t, store.GetLeague(), poker.League{{"chris", 3}, {"Cleo", 1}})
+	})
+	t.Run("renames and merges are written to the audit log", func(t *testing.T) {
+		store, _ := newStore(t)
+		assertNoError(t, store.RenamePlayer(ctx, "chris", "Christopher"))
+		assertNoError(t, store.MergePlayers(ctx, "Chris", []string{"Christopher"}))
+
+		audit, err := store.GetAuditLog(ctx)
+		assertNoError(t, err)
+		if len(audit) != 2 {
+			t.Fatalf("got %d audit entries, want 2", len(audit))
+		}
+		assertAuditEntry(t, audit[0], poker.AuditRename, []string{"chris"}, "Christopher")
+		assertAuditEntry(t, audit[1], poker.AuditMerge, []string{"Christopher"}, "Chris")
+	})
+}
+
+func TestPlayerEditEndpoints(t *testing.T) {
+	newStore := func() *poker.StubPlayerStore {
+		store := &poker.StubPlayerStore{}
+		store.RecordWin("chris")
+		store.RecordWin("Christopher")
+		store.RecordWin("Cleo")
+		return store
+	}
+
+	t.Run("PATCH /players/{name} renames a player", func(t *testing.T) {
+		store := newStore()
+		server := mustMakePlayerServer(t, store)
+
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newRenameRequest("chris", "Chris"))
+		assertStatus(t, response.Code, http.StatusOK)
+
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, newGetScoreRequest("Chris"))
+		assertResponseBody(t, response.Body.String(), "1")
+		assertAuditEntry(t, store.Audit[0], poker.AuditRename, []string{"chris"}, "Chris")
+	})
+	t.Run("POST /merge merges players", func(t *testing.T) {
+		store := newStore()
+		server := mustMakePlayerServer(t, store)
+
+		request, _ := http.NewRequest(http.MethodPost, "/merge", strings.NewReader(`{"into": "Chris", "from": ["chris", "Christopher"]}`))
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assertStatus(t, response.Code, http.StatusOK)
+
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, newGetScoreRequest("Chris"))
+		assertResponseBody(t, response.Body.String(), "2")
+	})
+	t.Run("returns errors for bad edits", func(t *testing.T) {
+		server := mustMakePlayerServer(t, newStore())
+		cases := map[string]struct {
+			request *http.Request
+			want    int
+		}{
+			"missing player":  {newRenameRequest("Apollo", "Chris"), http.StatusNotFound},
+			"name in use":     {newRenameRequest("chris", "Cleo"), http.StatusConflict},
+			"empty name":      {newRenameRequest("chris", ""), http.StatusBadRequest},
+			"nobody to merge": {httptest.NewRequest(http.MethodPost, "/merge", strings.NewReader(`{"into": "Chris"}`)), http.StatusBadRequest},
+		}
+		for name, c := range cases {
+			t.Run(name, func(t *testing.T) {
+				response := httptest.NewRecorder()
+				server.ServeHTTP(response, c.request)
+				assertStatus(t, response.Code, c.want)
+			})
+		}
+	})
+	t.Run("returns 501 when the store can't edit players", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &legacyPlayerStore{scores: map[string]int{"chris": 1}})
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newRenameRequest("chris", "Chris"))
+		assertStatus(t, response.Code, http.StatusNotImplemented)
+	})
+}
+
+func TestRunCommand(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rename and merge edit the store", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		store.RecordWin("chris")
+		store.RecordWin("Christopher")
+		out := &bytes.Buffer{}
+
+		assertNoError(t, poker.RunCommand(ctx, store, out, []string{"rename", "chris", "Chris"}))
+		assertNoError(t, poker.RunCommand(ctx, store, out, []string{"merge", "Chris", "Christopher"}))
+
+		assertLeague(t, store.GetLeague(), poker.League{{"Chris", 2}})
+		assertResponseBody(t, out.String(), "renamed chris to Chris\nmerged [Christopher] into Chris\n")
+	})
+	t.Run("returns store errors and rejects bad commands", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		out := &bytes.Buffer{}
+
+		assertError(t, poker.RunCommand(ctx, store, out, []string{"rename", "Apollo", "Chris"}), poker.ErrPlayerNotFound)
+		assertError(t, poker.RunCommand(ctx, store, out, []string{"juggle"}), poker.ErrUnknownCommand)
+		if err := poker.RunCommand(ctx, store, out, []string{"merge", "Chris"}); err == nil {
+			t.Error("expected an error merging nobody")
+		}
+	})
+}
+
+func assertAuditEntry(t testing.TB, got poker.AuditEntry, action poker.AuditAction, from []string, to string) {
+	t.Helper()
+	if got.Action != action || !reflect.DeepEqual(got.From, from) || got.To != to || got.Time.IsZero() {
+		t.Errorf("got audit entry %+v, want %s of %v to %s", got, action, from, to)
+	}
+}
+
+func newRenameRequest(from, to string) *http.Request {
+	req, _ := http.NewRequest(http.MethodPatch, "/players/"+from, strings.NewReader(`{"name": "`+to+`"}`))
+	return req
+}
@@ -0,0 +1,28 @@
+package poker
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// CleanPlayerName is how a new player's name is kept for display: trimmed,
+// with runs of whitespace collapsed to one space and in Unicode NFC
+func CleanPlayerName(name string) string {
+	return norm.NFC.String(strings.Join(strings.Fields(name), " "))
+}
+
+// PlayerKey identifies the player a name refers to. It's the cleaned name
+// case folded and in NFKC, so "Pepper", "pepper " and "PEPPER" are all the
+// same player. Stores look players up by key and keep the name the player
+// was first recorded under for display.
+func PlayerKey(name string) string {
+	folded := cases.Fold().String(norm.NFKC.String(CleanPlayerName(name)))
+	return norm.NFKC.String(folded)
+}
+
+// samePlayer reports whether names a and b refer to the same player
+func samePlayer(a, b string) bool {
+	return a == b || PlayerKey(a) == PlayerKey(b)
+}
@@ -0,0 +1,100 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"context"
+	"testing"
+)
+
+func TestPlayerKey(t *testing.T) {
+	cases := []struct {
+		a, b string
+		same bool
+	}{
+		{"Pepper", "pepper", true},
+		{"Pepper", "  PEPPER ", true},
+		{"Mary Jane", "mary   jane", true},
+		{"Zoë", "Zoe\u0308", true},
+		{"Straße", "STRASSE", true},
+		{"ｐｅｐｐｅｒ", "pepper", true},
+		{"Pepper", "Peppers", false},
+		{"Zoë", "Zoe", false},
+	}
+	for _, c := range cases {
+		t.Run(c.a+" and "+c.b, func(t *testing.T) {
+			if same := poker.PlayerKey(c.a) == poker.PlayerKey(c.b); same != c.same {
+				t.Errorf("got same player %v, want %v", same, c.same)
+			}
+		})
+	}
+}
+
+func TestCleanPlayerName(t *testing.T) {
+	for name, want := range map[string]string{
+		" Pepper ":     "Pepper",
+		"Mary \t Jane": "Mary Jane",
+		"Zoe\u0308":    "Zoë",
+		"Chris":        "Chris",
+	} {
+		if got := poker.CleanPlayerName(name); got != want {
+			t.Errorf("got %q cleaning %q, want %q", got, name, want)
+		}
+	}
+}
+
+func TestFileSystemStorePlayerKeys(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("players told apart by case in an old file become one", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, `[{"Name": "Pepper", "Wins": 3}, {"Name": "pepper", "Wins": 2}]`)
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		assertLeague(t, store.GetLeague(), poker.League{{"Pepper", 5}})
+	})
+	t.Run("games keep the name a player was first recorded under", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Pepper", "Cleo"}})
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{" cleo", "PEPPER"}})
+
+		games, err := store.GetGames(ctx, poker.GameFilter{Player: "CLEO"})
+		assertNoError(t, err)
+		if len(games) != 2 || games[1].Winner != "Cleo" || games[1].Finishing[1] != "Pepper" {
+			t.Errorf("got games %+v, want Cleo's games with display names", games)
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Cleo", 1}, {"Pepper", 1}})
+	})
+	t.Run("imported players are matched to carried over wins by key", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, `[{"Name": "Pepper", "Wins": 3}, {"Name": "Cleo", "Wins": 1}]`)
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		_, err = store.ImportLeague(ctx, poker.LeagueData{League: poker.League{{" PEPPER", 2}, {"Floyd", 1}}}, poker.ImportMerge, false)
+		assertNoError(t, err)
+		assertLeague(t, store.GetLeague(), poker.League{{"Pepper", 2}, {"Cleo", 1}, {"Floyd", 1}})
+	})
+	t.Run("a finishing order naming a player twice is invalid", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		_, err = store.RecordGame(ctx, poker.GameResult{Finishing: []string{"Pepper", "pepper"}})
+		assertError(t, err, poker.ErrInvalidGame)
+	})
+	t.Run("renaming can change the case of a name", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, `[{"Name": "pepper", "Wins": 3}]`)
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		assertNoError(t, store.RenamePlayer(ctx, "PEPPER", "Pepper"))
+		assertLeague(t, store.GetLeague(), poker.League{{"Pepper", 3}})
+	})
+}
@@ -0,0 +1,67 @@
+package poker
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPlayerNotFound is returned by a PlayerStoreV2 asked about a player it has no record of
+var ErrPlayerNotFound = errors.New("player not found")
+
+// PlayerStoreV2 is PlayerStore with cancellation and errors, so callers can
+// tell a missing player apart from a store that failed
+type PlayerStoreV2 interface {
+	GetPlayerScoreContext(ctx context.Context, name string) (int, error)
+	RecordWinContext(ctx context.Context, name string) error
+	GetLeagueContext(ctx context.Context) (League, error)
+	DeletePlayerContext(ctx context.Context, name string) error
+}
+
+// AdaptPlayerStore returns store as a PlayerStoreV2, wrapping stores that
+// only implement the original PlayerStore interface
+func AdaptPlayerStore(store PlayerStore) PlayerStoreV2 {
+	if v2, ok := store.(PlayerStoreV2); ok {
+		return v2
+	}
+	return &playerStoreAdapter{store}
+}
+
+// playerStoreAdapter can only report what PlayerStore tells it, so a score
+// of 0 is treated as the player not being found
+type playerStoreAdapter struct {
+	store PlayerStore
+}
+
+func (a *playerStoreAdapter) GetPlayerScoreContext(ctx context.Context, name string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	score := a.store.GetPlayerScore(name)
+	if score == 0 {
+		return 0, ErrPlayerNotFound
+	}
+	return score, nil
+}
+
+func (a *playerStoreAdapter) RecordWinContext(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	a.store.RecordWin(name)
+	return nil
+}
+
+func (a *playerStoreAdapter) GetLeagueContext(ctx context.Context) (League, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.store.GetLeague(), nil
+}
+
+func (a *playerStoreAdapter) DeletePlayerContext(ctx context.Context, name string) error {
+	if _, err := a.GetPlayerScoreContext(ctx, name); err != nil {
+		return err
+	}
+	a.store.DeletePlayer(name)
+	return nil
+}
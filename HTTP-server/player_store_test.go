@@ -0,0 +1,39 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlayerStoreContract(t *testing.T) {
+	t.Run("stub store", func(t *testing.T) {
+		poker.RunPlayerStoreSuite(t, func(t *testing.T) poker.PlayerStore {
+			return &poker.StubPlayerStore{}
+		})
+	})
+	t.Run("file system store", func(t *testing.T) {
+		poker.RunPlayerStoreSuite(t, func(t *testing.T) poker.PlayerStore {
+			database, cleanDatabase := createTempFile(t, "")
+			t.Cleanup(cleanDatabase)
+			store, err := poker.NewFileSystemPlayerStore(database)
+			assertNoError(t, err)
+			return store
+		})
+	})
+	t.Run("log store", func(t *testing.T) {
+		poker.RunPlayerStoreSuite(t, func(t *testing.T) poker.PlayerStore {
+			return mustMakeLogPlayerStore(t, createLogFile(t, ""))
+		})
+	})
+	t.Run("sqlite store", func(t *testing.T) {
+		poker.RunPlayerStoreSuite(t, func(t *testing.T) poker.PlayerStore {
+			return mustMakeSQLitePlayerStore(t, filepath.Join(t.TempDir(), "game.db"))
+		})
+	})
+	t.Run("postgres store", func(t *testing.T) {
+		poker.RunPlayerStoreSuite(t, func(t *testing.T) poker.PlayerStore {
+			return mustMakePostgresPlayerStore(t)
+		})
+	})
+}
@@ -0,0 +1,21 @@
+package poker
+
+// LeaguePoints scores games by the number of players each player beat.
+// Everyone in the finishing order gets a point for each player who finished
+// below them, including players who weren't named. When only the winner is
+// known they're scored for beating everyone else at the table, or one
+// player if the table size wasn't recorded.
+func LeaguePoints(games []GameResult) map[string]int {
+	points := map[string]int{}
+	for _, game := range games {
+		players := max(game.Players, len(game.Finishing))
+		if len(game.Finishing) == 0 {
+			points[game.Winner] += max(players-1, 1)
+			continue
+		}
+		for position, name := range game.Finishing {
+			points[name] += players - position - 1
+		}
+	}
+	return points
+}
@@ -0,0 +1,62 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLeaguePoints(t *testing.T) {
+	t.Run("everyone scores a point per player they beat", func(t *testing.T) {
+		got := poker.LeaguePoints([]poker.GameResult{
+			{Finishing: []string{"Cleo", "Chris", "Floyd"}},
+			{Finishing: []string{"Chris", "Cleo"}, Players: 4},
+		})
+		want := map[string]int{"Cleo": 4, "Chris": 4, "Floyd": 0}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got points %v, want %v", got, want)
+		}
+	})
+	t.Run("a winner only game scores the winner for the whole table", func(t *testing.T) {
+		got := poker.LeaguePoints([]poker.GameResult{
+			{Winner: "Cleo", Players: 6},
+			{Winner: "Chris"},
+		})
+		want := map[string]int{"Cleo": 5, "Chris": 1}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got points %v, want %v", got, want)
+		}
+	})
+}
+
+func TestLeagueSeasonPoints(t *testing.T) {
+	t.Run("league shows points from the current season", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		mustRecordGame(t, store, poker.GameResult{Winner: "Chris", Players: 8, Time: friday})
+		mustStartSeason(t, store, poker.Season{Name: "Q4", Start: time.Now().Add(-time.Hour)})
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Cleo", "Chris", "Floyd"}})
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Floyd", "Chris", "Cleo"}})
+		server := mustMakePlayerServer(t, store)
+
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newRequest(http.MethodGet, "/league?sort=points"))
+		assertStatus(t, response.Code, http.StatusOK)
+
+		got := map[string]int{}
+		var order []string
+		for _, standing := range getRatedLeagueFromResponse(t, response) {
+			got[standing.Name] = standing.Points
+			order = append(order, standing.Name)
+		}
+		want := map[string]int{"Chris": 2, "Cleo": 2, "Floyd": 2}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got points %v, want %v", got, want)
+		}
+		if order[0] != "Chris" {
+			t.Errorf("expected tied players to keep their League order, got %v", order)
+		}
+	})
+}
@@ -1,25 +1,37 @@
 package poker
 
-import "time"
+import (
+	"context"
+	"log"
+	"time"
+)
 
 type Game interface {
 	Start(numberOfPlayers int)
 	Finish(winner string)
+	// FinishRanking finishes the game with everyone in the order they finished, winner first
+	FinishRanking(finishing []string)
 }
 
 type PokerGame struct {
-	alerter BlindAlerter
-	store   PlayerStore
+	alerter         BlindAlerter
+	store           PlayerStore
+	numberOfPlayers int
+	// Source is recorded against every game finished, it defaults to SourceCLI
+	Source GameSource
 }
 
 func NewPokerGame(alerter BlindAlerter, store PlayerStore) *PokerGame {
 	return &PokerGame{
 		alerter: alerter,
 		store:   store,
+		Source:  SourceCLI,
 	}
 }
 
 func (p *PokerGame) Start(numberOfPlayers int) {
+	p.numberOfPlayers = numberOfPlayers
+
 	blindIncrement := time.Duration(5+numberOfPlayers) * time.Minute
 
 	blinds := []int{100, 200, 300, 400, 500, 600, 800, 1000, 2000, 4000, 8000}
@@ -30,6 +42,48 @@ func (p *PokerGame) Start(numberOfPlayers int) {
 	}
 }
 
+// Finish records the winner, along with the rest of the game when the store
+// keeps a history of games
 func (p *PokerGame) Finish(winner string) {
-	p.store.RecordWin(winner)
+	p.finish(GameResult{Winner: winner})
+}
+
+// FinishRanking records the whole finishing order when the store keeps a
+// history of games, otherwise just the winner
+func (p *PokerGame) FinishRanking(finishing []string) {
+	if len(finishing) == 0 {
+		return
+	}
+	p.finish(GameResult{Finishing: finishing})
+}
+
+func (p *PokerGame) finish(game GameResult) {
+	if _, err := p.FinishGame(context.Background(), game); err != nil {
+		log.Printf("problem recording game, %v", err)
+	}
+}
+
+// FinishGame records game, filling in how many played and where from when
+// they're not given, and returns it as recorded. Stores without a history
+// of games only record the winner.
+func (p *PokerGame) FinishGame(ctx context.Context, game GameResult) (GameResult, error) {
+	if game.Players == 0 {
+		game.Players = p.numberOfPlayers
+	}
+	if game.Players < len(game.Finishing) {
+		game.Players = len(game.Finishing)
+	}
+	if game.Source == "" {
+		game.Source = p.Source
+	}
+	game, err := game.normalise()
+	if err != nil {
+		return game, err
+	}
+
+	games, ok := p.store.(GameStore)
+	if !ok {
+		return game, AdaptPlayerStore(p.store).RecordWinContext(ctx, game.Winner)
+	}
+	return games.RecordGame(ctx, game)
 }
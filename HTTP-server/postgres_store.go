@@ -0,0 +1,166 @@
+package poker
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	_ "github.com/lib/pq"
+)
+
+const (
+	createPlayersTable = `CREATE TABLE IF NOT EXISTS players (
+	name TEXT PRIMARY KEY,
+	wins INTEGER NOT NULL DEFAULT 0
+)`
+	addPlayerKeyColumn = `ALTER TABLE players ADD COLUMN IF NOT EXISTS key TEXT`
+	createPlayerKeyIdx = `CREATE UNIQUE INDEX IF NOT EXISTS players_key ON players (key)`
+)
+
+// PostgresPlayerStore keeps the League in a Postgres players table
+type PostgresPlayerStore struct {
+	db *sql.DB
+}
+
+func PostgresPlayerStoreFromDSN(dsn string) (*PostgresPlayerStore, func(), error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("problem opening postgres connection, %v", err)
+	}
+
+	closeFunc := func() {
+		db.Close()
+	}
+
+	store, err := NewPostgresPlayerStore(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("problem creating postgres player store, %v", err)
+	}
+	return store, closeFunc, nil
+}
+
+func NewPostgresPlayerStore(db *sql.DB) (*PostgresPlayerStore, error) {
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("problem connecting to postgres, %v", err)
+	}
+	if _, err := db.Exec(createPlayersTable); err != nil {
+		return nil, fmt.Errorf("problem creating players table, %v", err)
+	}
+	if err := migratePostgresPlayerKeys(db); err != nil {
+		return nil, fmt.Errorf("problem adding player keys, %v", err)
+	}
+	return &PostgresPlayerStore{db: db}, nil
+}
+
+// migratePostgresPlayerKeys fills in the PlayerKey of players written before
+// players were looked up by key, merging the wins of players who only
+// differed by case or spacing into whichever already has the key
+func migratePostgresPlayerKeys(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(addPlayerKeyColumn); err != nil {
+		return err
+	}
+
+	type row struct {
+		name string
+		key  sql.NullString
+		wins int
+	}
+	var rows []row
+	result, err := tx.Query(`SELECT name, key, wins FROM players ORDER BY key IS NULL, name`)
+	if err != nil {
+		return err
+	}
+	for result.Next() {
+		var r row
+		if err := result.Scan(&r.name, &r.key, &r.wins); err != nil {
+			result.Close()
+			return err
+		}
+		rows = append(rows, r)
+	}
+	result.Close()
+	if err := result.Err(); err != nil {
+		return err
+	}
+
+	owners := map[string]string{}
+	for _, r := range rows {
+		if r.key.Valid {
+			owners[r.key.String] = r.name
+			continue
+		}
+		key := PlayerKey(r.name)
+		owner, ok := owners[key]
+		if !ok {
+			owners[key] = r.name
+			if _, err := tx.Exec(`UPDATE players SET key = $1 WHERE name = $2`, key, r.name); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE players SET wins = wins + $1 WHERE name = $2`, r.wins, owner); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM players WHERE name = $1`, r.name); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(createPlayerKeyIdx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (p *PostgresPlayerStore) GetLeague() League {
+	rows, err := p.db.Query(`SELECT name, wins FROM players ORDER BY wins DESC, name`)
+	if err != nil {
+		log.Printf("problem querying league, %v", err)
+		return nil
+	}
+	defer rows.Close()
+
+	league := League{}
+	for rows.Next() {
+		var player Player
+		if err := rows.Scan(&player.Name, &player.Wins); err != nil {
+			log.Printf("problem scanning player, %v", err)
+			return nil
+		}
+		league = append(league, player)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("problem reading league, %v", err)
+	}
+	return league
+}
+
+func (p *PostgresPlayerStore) GetPlayerScore(name string) int {
+	var wins int
+	err := p.db.QueryRow(`SELECT wins FROM players WHERE key = $1`, PlayerKey(name)).Scan(&wins)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("problem getting score for %s, %v", name, err)
+	}
+	return wins
+}
+
+func (p *PostgresPlayerStore) RecordWin(name string) {
+	_, err := p.db.Exec(`INSERT INTO players (name, key, wins) VALUES ($1, $2, 1)
+		ON CONFLICT (key) DO UPDATE SET wins = players.wins + 1`, CleanPlayerName(name), PlayerKey(name))
+	if err != nil {
+		log.Printf("problem recording win for %s, %v", name, err)
+	}
+}
+
+func (p *PostgresPlayerStore) DeletePlayer(name string) {
+	if _, err := p.db.Exec(`DELETE FROM players WHERE key = $1`, PlayerKey(name)); err != nil {
+		log.Printf("problem deleting player %s, %v", name, err)
+	}
+}
@@ -0,0 +1,94 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"fmt"
+	"net"
+	"os/exec"
+	"testing"
+)
+
+func TestPostgresStore(t *testing.T) {
+	t.Run("get League sorted by wins", func(t *testing.T) {
+		store := mustMakePostgresPlayerStore(t)
+		store.RecordWin("Cleo")
+		store.RecordWin("Chris")
+		store.RecordWin("Chris")
+
+		got := store.GetLeague()
+		want := poker.League{
+			{"Chris", 2},
+			{"Cleo", 1},
+		}
+		assertLeague(t, got, want)
+	})
+	t.Run("get score of unknown player is 0", func(t *testing.T) {
+		store := mustMakePostgresPlayerStore(t)
+		assertScoreEquals(t, store.GetPlayerScore("Apollo"), 0)
+	})
+	t.Run("store wins for existing players", func(t *testing.T) {
+		store := mustMakePostgresPlayerStore(t)
+		store.RecordWin("Pepper")
+		store.RecordWin("Pepper")
+		assertScoreEquals(t, store.GetPlayerScore("Pepper"), 2)
+	})
+	t.Run("delete player", func(t *testing.T) {
+		store := mustMakePostgresPlayerStore(t)
+		store.RecordWin("Pepper")
+		store.DeletePlayer("Pepper")
+		assertScoreEquals(t, store.GetPlayerScore("Pepper"), 0)
+		assertLeague(t, store.GetLeague(), poker.League{})
+	})
+}
+
+// mustMakePostgresPlayerStore starts a throwaway Postgres cluster in a temp
+// dir, skipping the test when the Postgres server binaries aren't installed.
+func mustMakePostgresPlayerStore(t *testing.T) *poker.PostgresPlayerStore {
+	t.Helper()
+	dsn := startPostgres(t)
+	store, closeFunc, err := poker.PostgresPlayerStoreFromDSN(dsn)
+	if err != nil {
+		t.Fatalf("could not create postgres player store: %v", err)
+	}
+	t.Cleanup(closeFunc)
+	return store
+}
+
+func startPostgres(t *testing.T) string {
+	t.Helper()
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		t.Skip("initdb not found, skipping postgres tests")
+	}
+	pgCtl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		t.Skip("pg_ctl not found, skipping postgres tests")
+	}
+
+	dir := t.TempDir()
+	dataDir := dir + "/data"
+	if out, err := exec.Command(initdb, "-D", dataDir, "-U", "postgres", "-A", "trust").CombinedOutput(); err != nil {
+		t.Skipf("could not initialise postgres cluster: %v\n%s", err, out)
+	}
+
+	port := freePort(t)
+	opts := fmt.Sprintf("-p %d -k %s -c listen_addresses=''", port, dir)
+	if out, err := exec.Command(pgCtl, "-D", dataDir, "-o", opts, "-w", "start").CombinedOutput(); err != nil {
+		t.Fatalf("could not start postgres: %v\n%s", err, out)
+	}
+	t.Cleanup(func() {
+		exec.Command(pgCtl, "-D", dataDir, "-m", "immediate", "stop").Run()
+	})
+
+	return fmt.Sprintf("host=%s port=%d user=postgres dbname=postgres sslmode=disable", dir, port)
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,45 @@
+package poker
+
+import "math"
+
+const (
+	// InitialRating is the Elo rating of a player before their first rated game
+	InitialRating = 1500
+	// eloK is the most a rating can move in a head to head game
+	eloK = 32
+)
+
+// EloRatings replays games, oldest first, and returns everyone's rating.
+// A game with a finishing order is scored as every player beating everyone
+// who finished below them, with K shared out so a big table moves a rating
+// about as much as a heads up game. Games without one aren't rated.
+func EloRatings(games []GameResult) map[string]float64 {
+	ratings := map[string]float64{}
+	rating := func(name string) float64 {
+		if r, ok := ratings[name]; ok {
+			return r
+		}
+		return InitialRating
+	}
+
+	for _, game := range games {
+		order := game.Finishing
+		if len(order) < 2 {
+			continue
+		}
+		k := eloK / float64(len(order)-1)
+		changes := make([]float64, len(order))
+		for i := range order {
+			for j := i + 1; j < len(order); j++ {
+				expected := 1 / (1 + math.Pow(10, (rating(order[j])-rating(order[i]))/400))
+				change := k * (1 - expected)
+				changes[i] += change
+				changes[j] -= change
+			}
+		}
+		for i, name := range order {
+			ratings[name] = rating(name) + changes[i]
+		}
+	}
+	return ratings
+}
@@ -0,0 +1,135 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEloRatings(t *testing.T) {
+	t.Run("an even heads up game moves both players by half of K", func(t *testing.T) {
+		ratings := poker.EloRatings([]poker.GameResult{
+			{Winner: "Cleo", Finishing: []string{"Cleo", "Chris"}},
+		})
+		assertRating(t, ratings, "Cleo", 1516)
+		assertRating(t, ratings, "Chris", 1484)
+	})
+	t.Run("ratings are zero sum and follow the finishing order", func(t *testing.T) {
+		ratings := poker.EloRatings([]poker.GameResult{
+			{Winner: "Cleo", Finishing: []string{"Cleo", "Chris", "Floyd", "Pepper"}},
+		})
+		total := 0.0
+		for _, r := range ratings {
+			total += r
+		}
+		if math.Abs(total-4*poker.InitialRating) > 1e-9 {
+			t.Errorf("got ratings totalling %v, want %v", total, 4*poker.InitialRating)
+		}
+		if !(ratings["Cleo"] > ratings["Chris"] && ratings["Chris"] > ratings["Floyd"] && ratings["Floyd"] > ratings["Pepper"]) {
+			t.Errorf("expected ratings to follow the finishing order, got %v", ratings)
+		}
+	})
+	t.Run("beating a stronger player is worth more", func(t *testing.T) {
+		ratings := poker.EloRatings([]poker.GameResult{
+			{Finishing: []string{"Chris", "Floyd"}},
+			{Finishing: []string{"Chris", "Floyd"}},
+			{Finishing: []string{"Cleo", "Chris"}},
+			{Finishing: []string{"Pepper", "Floyd"}},
+		})
+		if ratings["Cleo"]-poker.InitialRating <= ratings["Pepper"]-poker.InitialRating {
+			t.Errorf("expected beating Chris to be worth more than beating Floyd, got %v", ratings)
+		}
+	})
+	t.Run("games without a finishing order are not rated", func(t *testing.T) {
+		ratings := poker.EloRatings([]poker.GameResult{{Winner: "Cleo", Players: 5}})
+		if len(ratings) != 0 {
+			t.Errorf("expected no ratings, got %v", ratings)
+		}
+	})
+}
+
+func TestFinishingOrderValidation(t *testing.T) {
+	store := &poker.StubPlayerStore{}
+	cases := map[string]poker.GameResult{
+		"winner who didn't finish first": {Winner: "Chris", Finishing: []string{"Cleo", "Chris"}},
+		"player placed twice":            {Finishing: []string{"Cleo", "Chris", "Cleo"}},
+		"fewer players than placings":    {Players: 1, Finishing: []string{"Cleo", "Chris"}},
+		"no winner at all":               {},
+	}
+	for name, game := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := store.RecordGame(t.Context(), game)
+			assertError(t, err, poker.ErrInvalidGame)
+		})
+	}
+	t.Run("winner and players are filled in from the finishing order", func(t *testing.T) {
+		game, err := store.RecordGame(t.Context(), poker.GameResult{Finishing: []string{"Cleo", "Chris", "Floyd"}})
+		assertNoError(t, err)
+		if game.Winner != "Cleo" || game.Players != 3 {
+			t.Errorf("got winner %q with %d players, want Cleo with 3", game.Winner, game.Players)
+		}
+	})
+}
+
+func TestLeagueRatings(t *testing.T) {
+	store := &poker.StubPlayerStore{}
+	mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Chris", "Cleo"}})
+	mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Chris", "Cleo"}})
+	mustRecordGame(t, store, poker.GameResult{Winner: "Cleo", Players: 9})
+	mustRecordGame(t, store, poker.GameResult{Winner: "Cleo", Players: 9})
+	mustRecordGame(t, store, poker.GameResult{Winner: "Cleo", Players: 9})
+	server := mustMakePlayerServer(t, store)
+
+	t.Run("league includes each player's rating", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newLeagueRequest())
+		assertStatus(t, response.Code, http.StatusOK)
+
+		got := getRatedLeagueFromResponse(t, response)
+		if got[0].Name != "Cleo" || got[1].Name != "Chris" {
+			t.Fatalf("expected league sorted by wins, got %v", got)
+		}
+		if got[1].Rating <= poker.InitialRating || got[0].Rating >= poker.InitialRating {
+			t.Errorf("expected Chris rated above Cleo, got %v", got)
+		}
+	})
+	t.Run("sort=rating orders the league by rating", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newRequest(http.MethodGet, "/league?sort=rating"))
+		assertStatus(t, response.Code, http.StatusOK)
+
+		got := getRatedLeagueFromResponse(t, response)
+		if got[0].Name != "Chris" || got[1].Name != "Cleo" {
+			t.Errorf("expected league sorted by rating, got %v", got)
+		}
+	})
+	t.Run("returns 400 on an unknown sort", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newRequest(http.MethodGet, "/league?sort=vibes"))
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+	t.Run("returns 501 sorting by rating without a history of games", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &legacyPlayerStore{scores: map[string]int{}})
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newRequest(http.MethodGet, "/league?sort=rating"))
+		assertStatus(t, response.Code, http.StatusNotImplemented)
+	})
+}
+
+func getRatedLeagueFromResponse(t testing.TB, response *httptest.ResponseRecorder) (league []poker.Standing) {
+	t.Helper()
+	if err := json.NewDecoder(response.Body).Decode(&league); err != nil {
+		t.Fatalf("Unable to parse response from server %q into rated players, '%v'", response.Body, err)
+	}
+	return
+}
+
+func assertRating(t testing.TB, ratings map[string]float64, name string, want float64) {
+	t.Helper()
+	if got := ratings[name]; math.Abs(got-want) > 1e-9 {
+		t.Errorf("got rating %v for %s, want %v", got, name, want)
+	}
+}
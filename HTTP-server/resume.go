@@ -0,0 +1,132 @@
+package poker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultResumeWindow is how long a PlayerServer keeps a websocket
+	// session after its connection drops, for the client to resume it
+	DefaultResumeWindow = 5 * time.Minute
+	// DefaultSessionLimit is how many websocket sessions a PlayerServer
+	// keeps at once, connected or waiting to be resumed
+	DefaultSessionLimit = 1000
+)
+
+// errTooManySessions is returned opening a session when as many are kept as
+// allowed
+var errTooManySessions = errors.New("too many websocket sessions are open, try again later")
+
+// WithResumeWindow sets how long a websocket session can be resumed after
+// its connection drops, zero or less stops sessions being resumed at all
+func WithResumeWindow(window time.Duration) ServerOption {
+	return func(p *PlayerServer) {
+		p.resumeWindow = window
+	}
+}
+
+// WithSessionLimit sets how many websocket sessions can be kept at once,
+// counting those waiting to be resumed, zero or less lets any number be
+// kept
+func WithSessionLimit(sessions int) ServerOption {
+	return func(p *PlayerServer) {
+		p.sessionLimit = sessions
+	}
+}
+
+// wsSessions are the websocket sessions that can be resumed, by token. A
+// session's room is only closed when the session can't be resumed any more,
+// so a game in a room of its own carries on while a phone sleeps.
+type wsSessions struct {
+	mu       sync.Mutex
+	window   time.Duration
+	limit    int
+	sessions map[string]*resumableSession
+}
+
+// resumableSession is the room a session plays in, conn is nil while no
+// client is connected and expiry is running
+type resumableSession struct {
+	room      *gameRoom
+	private   bool
+	spectator bool
+	conn      *wsConn
+	expiry    *time.Timer
+}
+
+func newWSSessions(window time.Duration, limit int) *wsSessions {
+	return &wsSessions{window: window, limit: limit, sessions: map[string]*resumableSession{}}
+}
+
+// open starts a session for conn in room, returning its token, it fails with
+// errTooManySessions when limit sessions are already kept
+func (s *wsSessions) open(conn *wsConn, room *gameRoom, private, spectator bool) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limit > 0 && len(s.sessions) >= s.limit {
+		return "", errTooManySessions
+	}
+	s.sessions[token] = &resumableSession{room: room, private: private, spectator: spectator, conn: conn}
+	return token, nil
+}
+
+// resume hands the session token was given for to conn, disconnecting
+// whichever connection had it, and returns false when there's no such
+// session or its room has closed
+func (s *wsSessions) resume(token string, conn *wsConn) (*resumableSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[token]
+	if !ok {
+		return nil, false
+	}
+	if session.room.isClosed() {
+		delete(s.sessions, token)
+		return nil, false
+	}
+
+	if session.expiry != nil {
+		session.expiry.Stop()
+		session.expiry = nil
+	}
+	if session.conn != nil {
+		session.conn.Close()
+	}
+	session.conn = conn
+	return session, true
+}
+
+// drop is told conn has gone. Its session can be resumed until the window
+// passes, then it's forgotten and a room of its own is closed.
+func (s *wsSessions) drop(token string, conn *wsConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[token]
+	if !ok || session.conn != conn {
+		return
+	}
+
+	session.conn = nil
+	session.expiry = time.AfterFunc(s.window, func() {
+		s.mu.Lock()
+		expired := session.conn == nil && s.sessions[token] == session
+		if expired {
+			delete(s.sessions, token)
+		}
+		s.mu.Unlock()
+
+		if expired && session.private {
+			session.room.close()
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestResumingSessions(t *testing.T) {
+	newServer := func(t *testing.T, store *poker.StubPlayerStore, options ...poker.ServerOption) *httptest.Server {
+		t.Helper()
+		playerServer, err := poker.NewPlayerServer(store, options...)
+		assertNoError(t, err)
+		server := httptest.NewServer(playerServer)
+		t.Cleanup(server.Close)
+		return server
+	}
+	hello := func(session string) poker.WSMessage {
+		return poker.WSMessage{Type: poker.MsgHello, Version: poker.ProtocolVersion, Session: session}
+	}
+
+	t.Run("a resumed session is replayed the game in progress", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		url := wsURL(newServer(t, store))
+		ws, welcome := mustSayHello(t, url, hello(""))
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgStartGame, Players: 3})
+		readWSUntil(t, ws, poker.MsgBlind)
+		ws.Close()
+
+		ws, resumed := mustSayHello(t, url, hello(welcome.Session))
+		if !resumed.Resumed || resumed.Session != welcome.Session {
+			t.Fatalf("got %+v, want session %s resumed", resumed, welcome.Session)
+		}
+		assertWSMessage(t, readWS(t, ws), poker.WSMessage{Type: poker.MsgGameStarted, Players: 3, PlayersLeft: 3})
+		assertFirstBlind(t, readWS(t, ws), 8*time.Minute)
+
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgDeclareWinner, Winner: "Cleo"})
+		readWSUntil(t, ws, poker.MsgGameFinished)
+		poker.AssertPlayerWin(t, store, "Cleo")
+	})
+	t.Run("a game finished while away is replayed", func(t *testing.T) {
+		server := newServer(t, &poker.StubPlayerStore{})
+		room := mustCreateRoom(t, server)
+		dealer := mustHandshake(t, roomURL(server, room.ID))
+		phone, welcome := mustSayHello(t, roomURL(server, room.ID), hello(""))
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgStartGame, Players: 2})
+		readWSUntil(t, phone, poker.MsgBlind)
+		phone.Close()
+
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgDeclareWinner, Winner: "Cleo"})
+		readWSUntil(t, dealer, poker.MsgGameFinished)
+
+		phone, _ = mustSayHello(t, roomURL(server, room.ID), hello(welcome.Session))
+		if finished := readWS(t, phone); finished.Type != poker.MsgGameFinished || finished.Game.Winner != "Cleo" {
+			t.Errorf("got %+v, want Cleo's game finished", finished)
+		}
+	})
+	t.Run("a session can't be resumed once its window has passed", func(t *testing.T) {
+		url := wsURL(newServer(t, &poker.StubPlayerStore{}, poker.WithResumeWindow(10*time.Millisecond)))
+		ws, welcome := mustSayHello(t, url, hello(""))
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgStartGame, Players: 3})
+		readWSUntil(t, ws, poker.MsgBlind)
+		ws.Close()
+		time.Sleep(100 * time.Millisecond)
+
+		ws, fresh := mustSayHello(t, url, hello(welcome.Session))
+		if fresh.Resumed || fresh.Session == welcome.Session {
+			t.Fatalf("got %+v, want a new session", fresh)
+		}
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgStartGame, Players: 2})
+		assertWSMessage(t, readWS(t, ws), poker.WSMessage{Type: poker.MsgGameStarted, Players: 2, PlayersLeft: 2})
+	})
+	t.Run("a spectator resumes following the room's game", func(t *testing.T) {
+		server := newServer(t, &poker.StubPlayerStore{})
+		url := roomURL(server, mustCreateRoom(t, server).ID)
+		spectate := poker.WSMessage{Type: poker.MsgHello, Version: poker.ProtocolVersion, Role: poker.RoleSpectator}
+		clock, welcome := mustSayHello(t, url, spectate)
+		dealer := mustHandshake(t, url)
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgStartGame, Players: 4})
+		readWSUntil(t, clock, poker.MsgBlind)
+		clock.Close()
+
+		spectate.Session = welcome.Session
+		clock, resumed := mustSayHello(t, url, spectate)
+		if !resumed.Resumed {
+			t.Fatalf("got %+v, want the spectator's session resumed", resumed)
+		}
+		assertWSMessage(t, readWS(t, clock), poker.WSMessage{Type: poker.MsgGameStarted, Players: 4, PlayersLeft: 4})
+		readWSUntil(t, clock, poker.MsgBlind)
+		sendWS(t, clock, poker.WSMessage{Type: poker.MsgPlayerOut})
+		if got := readWSUntil(t, clock, poker.MsgError); got.Code != poker.WSErrorSpectator {
+			t.Errorf("got %+v, want the resumed session still a spectator", got)
+		}
+	})
+	t.Run("no more sessions are kept than the limit", func(t *testing.T) {
+		server := newServer(t, &poker.StubPlayerStore{}, poker.WithSessionLimit(1), poker.WithResumeWindow(50*time.Millisecond))
+		url := roomURL(server, mustCreateRoom(t, server).ID)
+		ws, welcome := mustSayHello(t, url, hello(""))
+		assertBusy(t, url)
+
+		ws.Close()
+		assertBusy(t, url)
+		ws, resumed := mustSayHello(t, url, hello(welcome.Session))
+		if !resumed.Resumed {
+			t.Fatalf("got %+v, want the session resumed while the limit is reached", resumed)
+		}
+
+		ws.Close()
+		time.Sleep(100 * time.Millisecond)
+		mustSayHello(t, url, hello(""))
+	})
+	t.Run("an unknown session starts a new one", func(t *testing.T) {
+		url := wsURL(newServer(t, &poker.StubPlayerStore{}))
+		_, welcome := mustSayHello(t, url, hello("nope"))
+		if welcome.Resumed || welcome.Session == "nope" {
+			t.Errorf("got %+v, want a new session", welcome)
+		}
+	})
+}
+
+func TestWebSocketHeartbeat(t *testing.T) {
+	newRoom := func(t *testing.T) (*httptest.Server, string) {
+		t.Helper()
+		playerServer, err := poker.NewPlayerServer(&poker.StubPlayerStore{}, poker.WithHeartbeat(20*time.Millisecond))
+		assertNoError(t, err)
+		server := httptest.NewServer(playerServer)
+		t.Cleanup(server.Close)
+		return server, mustCreateRoom(t, server).ID
+	}
+
+	t.Run("a client that stops answering pings is dropped", func(t *testing.T) {
+		server, room := newRoom(t)
+		mustHandshake(t, roomURL(server, room))
+
+		deadline := time.Now().Add(time.Second)
+		for roomClients(t, server, room) != 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("expected the client that stopped answering to be dropped")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+	t.Run("a client answering pings stays connected", func(t *testing.T) {
+		server, room := newRoom(t)
+		ws := mustHandshake(t, roomURL(server, room))
+
+		pings := 0
+		ws.SetPingHandler(func(string) error {
+			pings++
+			return ws.WriteControl(websocket.PongMessage, nil, time.Now().Add(time.Second))
+		})
+		ws.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		if _, _, err := ws.ReadMessage(); !strings.Contains(err.Error(), "timeout") {
+			t.Fatalf("got %v reading, want to time out waiting", err)
+		}
+		if pings == 0 {
+			t.Error("expected to be pinged")
+		}
+		if clients := roomClients(t, server, room); clients != 1 {
+			t.Errorf("got %d clients, want the client still connected", clients)
+		}
+	})
+}
+
+// assertBusy says hello on url and checks the server is too busy to open a
+// session
+func assertBusy(t *testing.T, url string) {
+	t.Helper()
+	ws := mustDialWS(t, url)
+	defer ws.Close()
+	sendWS(t, ws, poker.WSMessage{Type: poker.MsgHello, Version: poker.ProtocolVersion})
+	if got := readWS(t, ws); got.Type != poker.MsgError || got.Code != poker.WSErrorBusy {
+		t.Errorf("got %+v, want a busy error", got)
+	}
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+func roomClients(t *testing.T, server *httptest.Server, id string) int {
+	t.Helper()
+	var room poker.Room
+	getJSON(t, server.URL+"/rooms/"+id, http.StatusOK, &room)
+	return room.Clients
+}
@@ -0,0 +1,413 @@
+package poker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRoomLimit is how many game rooms a PlayerServer keeps open at once
+	DefaultRoomLimit = 1000
+	// DefaultRoomIdleTimeout is how long a game room stays open with nobody in it
+	DefaultRoomIdleTimeout = 30 * time.Minute
+)
+
+var (
+	// ErrRoomNotFound is returned for a game room that doesn't exist
+	ErrRoomNotFound = errors.New("room not found")
+	// ErrGameInProgress is returned starting a game in a room already playing one
+	ErrGameInProgress = errors.New("a game is already in progress, declare its winner first")
+	// ErrNoGame is returned declaring a winner in a room with no game in progress
+	ErrNoGame = errors.New("start a game before declaring its winner")
+	// ErrLastPlayer is returned knocking out the only player left in a game
+	ErrLastPlayer = errors.New("only one player is left, declare them the winner")
+	// ErrTooManyRooms is returned opening a room when as many are open as allowed
+	ErrTooManyRooms = errors.New("too many rooms are open, try again once one closes")
+)
+
+// WithRoomLimit sets how many game rooms can be open at once, zero or less
+// lets any number be opened
+func WithRoomLimit(rooms int) ServerOption {
+	return func(p *PlayerServer) {
+		p.roomLimit = rooms
+	}
+}
+
+// WithRoomIdleTimeout sets how long a game room stays open once everyone has
+// left it, zero or less keeps empty rooms open until they're deleted
+func WithRoomIdleTimeout(timeout time.Duration) ServerOption {
+	return func(p *PlayerServer) {
+		p.roomIdleTimeout = timeout
+	}
+}
+
+// Room describes a game room for GET /rooms
+type Room struct {
+	ID          string `json:"id"`
+	Playing     bool   `json:"playing"`
+	Players     int    `json:"players,omitempty"`
+	PlayersLeft int    `json:"players_left,omitempty"`
+	Clients     int    `json:"clients"`
+}
+
+// gameRoom is a table playing one PokerGame at a time, every websocket in
+// the room is told when its game starts and finishes and sent its blinds
+type gameRoom struct {
+	id      string
+	newGame func(alerter BlindAlerter) *PokerGame
+	// onIdle is called once the room has been empty for idleTimeout and
+	// onClose once the room is closed
+	onIdle      func()
+	idleTimeout time.Duration
+	onClose     func()
+
+	// finishing is held while a winner is declared, so the game is only
+	// recorded once
+	finishing sync.Mutex
+
+	mu          sync.Mutex
+	idle        *time.Timer
+	clients     map[*wsConn]bool
+	game        *PokerGame
+	alerter     *wsBlindAlerter
+	players     int
+	playersLeft int
+	// finished is the last game played, for clients joining between games
+	finished *GameResult
+	closed   bool
+}
+
+func newGameRoom(id string, newGame func(alerter BlindAlerter) *PokerGame) *gameRoom {
+	return &gameRoom{id: id, newGame: newGame, clients: map[*wsConn]bool{}}
+}
+
+func (g *gameRoom) describe() Room {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return Room{ID: g.id, Playing: g.game != nil, Players: g.players, PlayersLeft: g.playersLeft, Clients: len(g.clients)}
+}
+
+// join adds client to the room, telling it about the game in progress or
+// else the last game finished, and returns false when the room has been
+// closed
+func (g *gameRoom) join(client *wsConn) (bool, error) {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return false, nil
+	}
+	g.clients[client] = true
+	g.stopIdling()
+	var messages []WSMessage
+	if g.game != nil {
+		messages = append(messages, g.started())
+		if blind, ok := g.alerter.current(); ok {
+			messages = append(messages, blind)
+		}
+	} else if g.finished != nil {
+		messages = append(messages, WSMessage{Type: MsgGameFinished, Game: g.finished})
+	}
+	g.mu.Unlock()
+
+	for _, message := range messages {
+		if err := client.send(message); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func (g *gameRoom) leave(client *wsConn) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.clients, client)
+	if len(g.clients) == 0 && !g.closed {
+		g.startIdling()
+	}
+}
+
+// start starts a game for players players and sends its blinds to everyone
+// in the room
+func (g *gameRoom) start(players int) error {
+	g.mu.Lock()
+	if g.game != nil {
+		g.mu.Unlock()
+		return ErrGameInProgress
+	}
+	alerter := newWSBlindAlerter(g.sendAll)
+	g.alerter = alerter
+	g.game, g.players, g.playersLeft = g.newGame(alerter), players, players
+	g.game.Start(players)
+	started, clients := g.started(), g.clientList()
+	g.mu.Unlock()
+
+	// the blinds only start once everyone has been told about the game, a
+	// game finished in the meantime has already stopped them
+	send(clients, started)
+	alerter.start()
+	return nil
+}
+
+// knockOut takes a player out of the game in progress and tells everyone in
+// the room how many are left, player is only a name to show
+func (g *gameRoom) knockOut(player string) error {
+	g.mu.Lock()
+	if g.game == nil {
+		g.mu.Unlock()
+		return ErrNoGame
+	}
+	if g.playersLeft <= 1 {
+		g.mu.Unlock()
+		return ErrLastPlayer
+	}
+	g.playersLeft--
+	out, clients := WSMessage{Type: MsgPlayerOut, Player: player, PlayersLeft: g.playersLeft}, g.clientList()
+	g.mu.Unlock()
+
+	send(clients, out)
+	return nil
+}
+
+// finish records the game in progress with record and tells everyone in the
+// room who won, the game carries on if it couldn't be recorded. The room
+// isn't locked while the game is recorded, only one winner is declared at
+// a time.
+func (g *gameRoom) finish(ctx context.Context, record func(game *PokerGame) (GameResult, error)) error {
+	g.finishing.Lock()
+	defer g.finishing.Unlock()
+
+	g.mu.Lock()
+	game := g.game
+	g.mu.Unlock()
+	if game == nil {
+		return ErrNoGame
+	}
+
+	result, err := record(game)
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	if g.game != game {
+		// the room was closed while the game was recorded
+		g.mu.Unlock()
+		return nil
+	}
+	g.endGame()
+	g.finished = &result
+	clients := g.clientList()
+	g.mu.Unlock()
+
+	send(clients, WSMessage{Type: MsgGameFinished, Game: &result})
+	return nil
+}
+
+// close ends the game in progress and disconnects everyone in the room
+func (g *gameRoom) close() {
+	g.mu.Lock()
+	g.endGame()
+	g.stopIdling()
+	wasOpen := !g.closed
+	g.closed = true
+	clients := g.clientList()
+	g.mu.Unlock()
+
+	for _, client := range clients {
+		client.Close()
+	}
+	if wasOpen && g.onClose != nil {
+		g.onClose()
+	}
+}
+
+// closeIdle closes the room if nobody is in it, returning whether it did
+func (g *gameRoom) closeIdle() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.clients) > 0 || g.closed {
+		return false
+	}
+	g.endGame()
+	g.closed = true
+	return true
+}
+
+func (g *gameRoom) isClosed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.closed
+}
+
+// startIdling calls onIdle once the room has been empty for idleTimeout,
+// rooms without an onIdle stay open while they're empty
+func (g *gameRoom) startIdling() {
+	if g.onIdle == nil || g.idleTimeout <= 0 {
+		return
+	}
+	g.stopIdling()
+	g.idle = time.AfterFunc(g.idleTimeout, g.onIdle)
+}
+
+func (g *gameRoom) stopIdling() {
+	if g.idle != nil {
+		g.idle.Stop()
+		g.idle = nil
+	}
+}
+
+// endGame stops the blinds of the game in progress, if there is one
+func (g *gameRoom) endGame() {
+	if g.alerter != nil {
+		g.alerter.stop()
+	}
+	g.game, g.alerter, g.players, g.playersLeft = nil, nil, 0, 0
+}
+
+func (g *gameRoom) started() WSMessage {
+	return WSMessage{Type: MsgGameStarted, Players: g.players, PlayersLeft: g.playersLeft}
+}
+
+// clientList is everyone in the room, to send to once it's unlocked
+func (g *gameRoom) clientList() []*wsConn {
+	clients := make([]*wsConn, 0, len(g.clients))
+	for client := range g.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// sendAll sends message to everyone in the room
+func (g *gameRoom) sendAll(message WSMessage) {
+	g.mu.Lock()
+	clients := g.clientList()
+	g.mu.Unlock()
+	send(clients, message)
+}
+
+func send(clients []*wsConn, message WSMessage) {
+	for _, client := range clients {
+		if err := client.send(message); err != nil {
+			logWSError(err)
+		}
+	}
+}
+
+// gameRooms are the rooms of a PlayerServer, many can play at once. Up to
+// limit are open at a time, counting the private rooms of single websockets,
+// and each listed room is closed once it has been empty for idleTimeout.
+type gameRooms struct {
+	mu           sync.Mutex
+	rooms        map[string]*gameRoom
+	privateRooms int
+	newGame      func(alerter BlindAlerter) *PokerGame
+	limit        int
+	idleTimeout  time.Duration
+}
+
+func newGameRooms(newGame func(alerter BlindAlerter) *PokerGame, limit int, idleTimeout time.Duration) *gameRooms {
+	return &gameRooms{rooms: map[string]*gameRoom{}, newGame: newGame, limit: limit, idleTimeout: idleTimeout}
+}
+
+// create opens a new room with a random ID, it fails with ErrTooManyRooms
+// when limit rooms are already open
+func (r *gameRooms) create() (*gameRoom, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	room := newGameRoom(hex.EncodeToString(id), r.newGame)
+	room.idleTimeout = r.idleTimeout
+	room.onIdle = func() { r.closeIdle(room) }
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.full() {
+		return nil, ErrTooManyRooms
+	}
+	r.rooms[room.id] = room
+	// nobody is in the room until its first player joins
+	room.mu.Lock()
+	room.startIdling()
+	room.mu.Unlock()
+	return room, nil
+}
+
+// closeIdle closes room and forgets it unless someone joined it as it idled
+func (r *gameRooms) closeIdle(room *gameRoom) {
+	if !room.closeIdle() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.rooms[room.id] == room {
+		delete(r.rooms, room.id)
+	}
+}
+
+// private opens a room for a single websocket, it isn't listed and can't
+// be joined. It counts towards limit until it's closed.
+func (r *gameRooms) private() (*gameRoom, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.full() {
+		return nil, ErrTooManyRooms
+	}
+	r.privateRooms++
+	room := newGameRoom("", r.newGame)
+	room.onClose = func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.privateRooms--
+	}
+	return room, nil
+}
+
+// full says whether limit rooms are open, r.mu must be held
+func (r *gameRooms) full() bool {
+	return r.limit > 0 && len(r.rooms)+r.privateRooms >= r.limit
+}
+
+func (r *gameRooms) find(id string) (*gameRoom, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	room, ok := r.rooms[id]
+	if !ok {
+		return nil, ErrRoomNotFound
+	}
+	return room, nil
+}
+
+// list describes every room, ordered by ID
+func (r *gameRooms) list() []Room {
+	r.mu.Lock()
+	rooms := make([]*gameRoom, 0, len(r.rooms))
+	for _, room := range r.rooms {
+		rooms = append(rooms, room)
+	}
+	r.mu.Unlock()
+
+	described := make([]Room, len(rooms))
+	for i, room := range rooms {
+		described[i] = room.describe()
+	}
+	sort.Slice(described, func(i, j int) bool { return described[i].ID < described[j].ID })
+	return described
+}
+
+func (r *gameRooms) remove(id string) error {
+	r.mu.Lock()
+	room, ok := r.rooms[id]
+	delete(r.rooms, id)
+	r.mu.Unlock()
+	if !ok {
+		return ErrRoomNotFound
+	}
+	room.close()
+	return nil
+}
@@ -0,0 +1,61 @@
+package poker
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// roomsHandler lists the game rooms on GET and opens a new one on POST,
+// players join it on /ws?room={id}
+func (p *PlayerServer) roomsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, p.rooms.list())
+	case http.MethodPost:
+		room, err := p.rooms.create()
+		if err != nil {
+			roomError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, room.describe())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// roomHandler describes /rooms/{id} on GET, DELETE ends its game and
+// disconnects everyone in it
+func (p *PlayerServer) roomHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/rooms/")
+	switch r.Method {
+	case http.MethodGet:
+		room, err := p.rooms.find(id)
+		if err != nil {
+			roomError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, room.describe())
+	case http.MethodDelete:
+		if err := p.rooms.remove(id); err != nil {
+			roomError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func roomError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrRoomNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, ErrTooManyRooms) {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	storeError(w, err)
+}
@@ -0,0 +1,358 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestGameRooms(t *testing.T) {
+	newServer := func(t *testing.T, store *poker.StubPlayerStore) *httptest.Server {
+		t.Helper()
+		server := httptest.NewServer(mustMakePlayerServer(t, store))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	t.Run("rooms are opened and listed", func(t *testing.T) {
+		server := newServer(t, &poker.StubPlayerStore{})
+		first := mustCreateRoom(t, server)
+		second := mustCreateRoom(t, server)
+		if first.ID == "" || first.ID == second.ID {
+			t.Fatalf("got room IDs %q and %q, want two different IDs", first.ID, second.ID)
+		}
+
+		var rooms []poker.Room
+		getJSON(t, server.URL+"/rooms", http.StatusOK, &rooms)
+		if len(rooms) != 2 {
+			t.Fatalf("got rooms %+v, want 2", rooms)
+		}
+		var room poker.Room
+		getJSON(t, server.URL+"/rooms/"+first.ID, http.StatusOK, &room)
+		if room.ID != first.ID || room.Playing {
+			t.Errorf("got room %+v, want %s not playing", room, first.ID)
+		}
+		getJSON(t, server.URL+"/rooms/nope", http.StatusNotFound, nil)
+	})
+	t.Run("everyone in a room sees its game start, its blinds and its winner", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := newServer(t, store)
+		room := mustCreateRoom(t, server)
+		dealer := mustHandshake(t, roomURL(server, room.ID))
+		player := mustHandshake(t, roomURL(server, room.ID))
+
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgStartGame, Players: 3})
+		for _, ws := range []*websocket.Conn{dealer, player} {
+			assertWSMessage(t, readWS(t, ws), poker.WSMessage{Type: poker.MsgGameStarted, Players: 3, PlayersLeft: 3})
+			assertFirstBlind(t, readWS(t, ws), 8*time.Minute)
+		}
+
+		sendWS(t, player, poker.WSMessage{Type: poker.MsgDeclareWinner, Winner: "Cleo"})
+		for _, ws := range []*websocket.Conn{dealer, player} {
+			if finished := readWSUntil(t, ws, poker.MsgGameFinished); finished.Game == nil || finished.Game.Winner != "Cleo" {
+				t.Errorf("got %+v, want Cleo's game finished", finished)
+			}
+		}
+		poker.AssertPlayerWin(t, store, "Cleo")
+	})
+	t.Run("a declaration sent again is only answered to its sender", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := newServer(t, store)
+		room := mustCreateRoom(t, server)
+		dealer := mustHandshake(t, roomURL(server, room.ID))
+		player := mustHandshake(t, roomURL(server, room.ID))
+
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgStartGame, Players: 2})
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgDeclareWinner, ID: "game-1", Winner: "Cleo"})
+		readWSUntil(t, player, poker.MsgGameFinished)
+		readWSUntil(t, dealer, poker.MsgGameFinished)
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgDeclareWinner, ID: "game-1", Winner: "Cleo"})
+		readWSUntil(t, dealer, poker.MsgGameFinished)
+
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgStartGame, Players: 3})
+		if got := readWS(t, player); got.Type != poker.MsgGameStarted {
+			t.Errorf("got %+v, want the next game started and no replay", got)
+		}
+		poker.AssertPlayerWin(t, store, "Cleo")
+	})
+	t.Run("rooms play their games independently", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := newServer(t, store)
+		one := mustHandshake(t, roomURL(server, mustCreateRoom(t, server).ID))
+		two := mustHandshake(t, roomURL(server, mustCreateRoom(t, server).ID))
+
+		sendWS(t, one, poker.WSMessage{Type: poker.MsgStartGame, Players: 3})
+		readWSUntil(t, one, poker.MsgGameStarted)
+		sendWS(t, two, poker.WSMessage{Type: poker.MsgStartGame, Players: 5})
+		assertWSMessage(t, readWS(t, two), poker.WSMessage{Type: poker.MsgGameStarted, Players: 5, PlayersLeft: 5})
+		assertFirstBlind(t, readWS(t, two), 10*time.Minute)
+
+		sendWS(t, two, poker.WSMessage{Type: poker.MsgDeclareWinner, Winner: "Chris"})
+		readWSUntil(t, two, poker.MsgGameFinished)
+		sendWS(t, one, poker.WSMessage{Type: poker.MsgDeclareWinner, Winner: "Cleo"})
+		if finished := readWSUntil(t, one, poker.MsgGameFinished); finished.Game.Players != 3 {
+			t.Errorf("got %+v, want the 3 player game finished", finished.Game)
+		}
+		if len(store.WinCalls) != 2 {
+			t.Errorf("got wins %v, want one from each room", store.WinCalls)
+		}
+	})
+	t.Run("a player joining mid game is told about it", func(t *testing.T) {
+		server := newServer(t, &poker.StubPlayerStore{})
+		room := mustCreateRoom(t, server)
+		dealer := mustHandshake(t, roomURL(server, room.ID))
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgStartGame, Players: 3})
+		readWSUntil(t, dealer, poker.MsgBlind)
+
+		late := mustHandshake(t, roomURL(server, room.ID))
+		assertWSMessage(t, readWS(t, late), poker.WSMessage{Type: poker.MsgGameStarted, Players: 3, PlayersLeft: 3})
+		assertFirstBlind(t, readWS(t, late), 8*time.Minute)
+
+		var described poker.Room
+		getJSON(t, server.URL+"/rooms/"+room.ID, http.StatusOK, &described)
+		if !described.Playing || described.Players != 3 || described.Clients != 2 {
+			t.Errorf("got room %+v, want a 3 player game with 2 clients", described)
+		}
+	})
+	t.Run("an unknown room can't be joined", func(t *testing.T) {
+		server := newServer(t, &poker.StubPlayerStore{})
+		_, response, err := websocket.DefaultDialer.Dial(roomURL(server, "nope"), nil)
+		if err == nil {
+			t.Fatal("expected joining an unknown room to fail")
+		}
+		if response == nil || response.StatusCode != http.StatusNotFound {
+			t.Errorf("got response %v, want status %d", response, http.StatusNotFound)
+		}
+	})
+	t.Run("deleting a room disconnects everyone in it", func(t *testing.T) {
+		server := newServer(t, &poker.StubPlayerStore{})
+		room := mustCreateRoom(t, server)
+		ws := mustHandshake(t, roomURL(server, room.ID))
+
+		request, _ := http.NewRequest(http.MethodDelete, server.URL+"/rooms/"+room.ID, nil)
+		response, err := http.DefaultClient.Do(request)
+		assertNoError(t, err)
+		response.Body.Close()
+		assertStatus(t, response.StatusCode, http.StatusOK)
+
+		ws.SetReadDeadline(time.Now().Add(time.Second))
+		if _, _, err := ws.ReadMessage(); err == nil {
+			t.Error("expected the connection closed with its room")
+		}
+		getJSON(t, server.URL+"/rooms/"+room.ID, http.StatusNotFound, nil)
+	})
+}
+
+func TestRoomLimits(t *testing.T) {
+	newServer := func(t *testing.T, options ...poker.ServerOption) *httptest.Server {
+		t.Helper()
+		playerServer, err := poker.NewPlayerServer(&poker.StubPlayerStore{}, options...)
+		assertNoError(t, err)
+		server := httptest.NewServer(playerServer)
+		t.Cleanup(server.Close)
+		return server
+	}
+	// waitForRoom polls the room until it has the status wanted
+	waitForRoom := func(t *testing.T, server *httptest.Server, id string, status int) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			response, err := http.Get(server.URL + "/rooms/" + id)
+			assertNoError(t, err)
+			response.Body.Close()
+			if response.StatusCode == status {
+				return
+			}
+		}
+		t.Fatalf("room %s never had status %d", id, status)
+	}
+
+	t.Run("no more rooms open than the limit", func(t *testing.T) {
+		server := newServer(t, poker.WithRoomLimit(1))
+		room := mustCreateRoom(t, server)
+
+		response, err := http.Post(server.URL+"/rooms", "", nil)
+		assertNoError(t, err)
+		response.Body.Close()
+		assertStatus(t, response.StatusCode, http.StatusServiceUnavailable)
+		if response.Header.Get("Retry-After") == "" {
+			t.Error("expected a Retry-After header")
+		}
+
+		request, _ := http.NewRequest(http.MethodDelete, server.URL+"/rooms/"+room.ID, nil)
+		response, err = http.DefaultClient.Do(request)
+		assertNoError(t, err)
+		response.Body.Close()
+		mustCreateRoom(t, server)
+	})
+	t.Run("private rooms count towards the limit", func(t *testing.T) {
+		server := newServer(t, poker.WithRoomLimit(1), poker.WithResumeWindow(20*time.Millisecond))
+		ws := mustHandshake(t, wsURL(server))
+
+		response, err := http.Post(server.URL+"/rooms", "", nil)
+		assertNoError(t, err)
+		response.Body.Close()
+		assertStatus(t, response.StatusCode, http.StatusServiceUnavailable)
+		assertBusy(t, wsURL(server))
+
+		// the private room closes once its session can't be resumed
+		ws.Close()
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if response, err = http.Post(server.URL+"/rooms", "", nil); err == nil {
+				response.Body.Close()
+				if response.StatusCode == http.StatusCreated {
+					return
+				}
+			}
+		}
+		t.Error("expected a room could be opened once the private room closed")
+	})
+	t.Run("a room nobody joins is closed once idle", func(t *testing.T) {
+		server := newServer(t, poker.WithRoomIdleTimeout(20*time.Millisecond))
+		room := mustCreateRoom(t, server)
+		waitForRoom(t, server, room.ID, http.StatusNotFound)
+	})
+	t.Run("a room stays open while someone is in it and closes once they leave", func(t *testing.T) {
+		server := newServer(t, poker.WithRoomIdleTimeout(50*time.Millisecond))
+		room := mustCreateRoom(t, server)
+		ws := mustHandshake(t, roomURL(server, room.ID))
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgStartGame, Players: 3})
+		readWSUntil(t, ws, poker.MsgBlind)
+
+		time.Sleep(100 * time.Millisecond)
+		var open poker.Room
+		getJSON(t, server.URL+"/rooms/"+room.ID, http.StatusOK, &open)
+		if !open.Playing {
+			t.Errorf("got room %+v, want its game still playing", open)
+		}
+
+		ws.Close()
+		waitForRoom(t, server, room.ID, http.StatusNotFound)
+	})
+	t.Run("idle rooms make way for new ones", func(t *testing.T) {
+		server := newServer(t, poker.WithRoomLimit(1), poker.WithRoomIdleTimeout(20*time.Millisecond))
+		room := mustCreateRoom(t, server)
+		waitForRoom(t, server, room.ID, http.StatusNotFound)
+		mustCreateRoom(t, server)
+	})
+}
+
+func mustCreateRoom(t *testing.T, server *httptest.Server) poker.Room {
+	t.Helper()
+	response, err := http.Post(server.URL+"/rooms", "", nil)
+	assertNoError(t, err)
+	defer response.Body.Close()
+	assertStatus(t, response.StatusCode, http.StatusCreated)
+	var room poker.Room
+	if err := json.NewDecoder(response.Body).Decode(&room); err != nil {
+		t.Fatalf("could not decode room, %v", err)
+	}
+	return room
+}
+
+func roomURL(server *httptest.Server, id string) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?room=" + id
+}
+
+// getJSON gets url, checking its status and decoding its body into v unless
+// v is nil
+func getJSON(t *testing.T, url string, status int, v any) {
+	t.Helper()
+	response, err := http.Get(url)
+	assertNoError(t, err)
+	defer response.Body.Close()
+	assertStatus(t, response.StatusCode, status)
+	if v == nil {
+		return
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		t.Fatalf("could not decode %s, %v", url, err)
+	}
+}
+
+func TestSpectators(t *testing.T) {
+	newRoom := func(t *testing.T) (*httptest.Server, string) {
+		t.Helper()
+		server := httptest.NewServer(mustMakePlayerServer(t, &poker.StubPlayerStore{}))
+		t.Cleanup(server.Close)
+		return server, mustCreateRoom(t, server).ID
+	}
+
+	t.Run("a spectator follows the room's game without playing it", func(t *testing.T) {
+		server, room := newRoom(t)
+		dealer := mustHandshake(t, roomURL(server, room))
+		tv := mustSpectate(t, roomURL(server, room))
+
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgStartGame, Players: 3})
+		assertWSMessage(t, readWS(t, tv), poker.WSMessage{Type: poker.MsgGameStarted, Players: 3, PlayersLeft: 3})
+		assertFirstBlind(t, readWS(t, tv), 8*time.Minute)
+
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgPlayerOut, Player: "Chris"})
+		assertWSMessage(t, readWS(t, tv), poker.WSMessage{Type: poker.MsgPlayerOut, Player: "Chris", PlayersLeft: 2})
+
+		for _, message := range []poker.WSMessage{
+			{Type: poker.MsgStartGame, Players: 2},
+			{Type: poker.MsgPlayerOut},
+			{Type: poker.MsgDeclareWinner, Winner: "Cleo"},
+		} {
+			sendWS(t, tv, message)
+			if got := readWS(t, tv); got.Code != poker.WSErrorSpectator {
+				t.Errorf("spectator sending %+v got %+v, want a spectator error", message, got)
+			}
+		}
+
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgDeclareWinner, Winner: "Cleo"})
+		if finished := readWS(t, tv); finished.Type != poker.MsgGameFinished || finished.Game.Winner != "Cleo" {
+			t.Errorf("got %+v, want Cleo's game finished", finished)
+		}
+	})
+	t.Run("a spectator joining mid game is shown where it's up to", func(t *testing.T) {
+		server, room := newRoom(t)
+		dealer := mustHandshake(t, roomURL(server, room))
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgStartGame, Players: 4})
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgPlayerOut})
+		readWSUntil(t, dealer, poker.MsgPlayerOut)
+
+		tv := mustSpectate(t, roomURL(server, room))
+		assertWSMessage(t, readWS(t, tv), poker.WSMessage{Type: poker.MsgGameStarted, Players: 4, PlayersLeft: 3})
+		assertFirstBlind(t, readWS(t, tv), 9*time.Minute)
+	})
+	t.Run("the last player can't be knocked out", func(t *testing.T) {
+		server, room := newRoom(t)
+		dealer := mustHandshake(t, roomURL(server, room))
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgPlayerOut})
+		if got := readWSUntil(t, dealer, poker.MsgError); got.Code != poker.WSErrorNoGame {
+			t.Errorf("got %+v, want no game", got)
+		}
+
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgStartGame, Players: 2})
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgPlayerOut})
+		readWSUntil(t, dealer, poker.MsgPlayerOut)
+		sendWS(t, dealer, poker.WSMessage{Type: poker.MsgPlayerOut})
+		if got := readWSUntil(t, dealer, poker.MsgError); got.Code != poker.WSErrorInvalidGame {
+			t.Errorf("got %+v, want an invalid game", got)
+		}
+	})
+	t.Run("spectators must follow a room", func(t *testing.T) {
+		server, _ := newRoom(t)
+		ws := mustDialWS(t, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws")
+		defer ws.Close()
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgHello, Version: poker.ProtocolVersion, Role: poker.RoleSpectator})
+		if got := readWS(t, ws); got.Type != poker.MsgError {
+			t.Errorf("got %+v, want an error", got)
+		}
+	})
+}
+
+// mustSpectate dials url and says hello as a spectator, failing unless
+// welcomed
+func mustSpectate(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	ws, _ := mustSayHello(t, url, poker.WSMessage{Type: poker.MsgHello, Version: poker.ProtocolVersion, Role: poker.RoleSpectator})
+	return ws
+}
@@ -0,0 +1,108 @@
+package poker
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// DefaultScoring is the players beaten scoring of LeaguePoints, used until a
+// League or season picks another
+const DefaultScoring = "players-beaten"
+
+// ErrUnknownScoring is returned when asked for a scoring that doesn't exist
+var ErrUnknownScoring = errors.New("unknown scoring, want wins, players-beaten or f1")
+
+// Scoring turns a finished game into the league points each player scored
+type Scoring interface {
+	Score(game GameResult) map[string]int
+}
+
+type ScoringFunc func(game GameResult) map[string]int
+
+func (s ScoringFunc) Score(game GameResult) map[string]int {
+	return s(game)
+}
+
+var scorings = map[string]Scoring{
+	"wins":           ScoringFunc(WinsScoring),
+	"players-beaten": ScoringFunc(PlayersBeatenScoring),
+	"f1":             ScoringFunc(F1Scoring),
+}
+
+// ScoringStore is implemented by stores that score their League with a
+// configurable Scoring, a season's own scoring overrides the League's
+type ScoringStore interface {
+	// GetScoring returns the name of the scoring in use right now
+	GetScoring(ctx context.Context) (string, error)
+	// SetScoring changes the League's scoring, seasons with their own keep it
+	SetScoring(ctx context.Context, name string) error
+	// GetPoints returns everyone's points in the League right now
+	GetPoints(ctx context.Context) (map[string]int, error)
+}
+
+// ScoringByName looks up one of the built in scorings, an empty name is DefaultScoring
+func ScoringByName(name string) (Scoring, error) {
+	if name == "" {
+		name = DefaultScoring
+	}
+	scoring, ok := scorings[name]
+	if !ok {
+		return nil, ErrUnknownScoring
+	}
+	return scoring, nil
+}
+
+// WinsScoring gives the winner a point
+func WinsScoring(game GameResult) map[string]int {
+	return map[string]int{game.Winner: 1}
+}
+
+// PlayersBeatenScoring scores a single game the way LeaguePoints does
+func PlayersBeatenScoring(game GameResult) map[string]int {
+	return LeaguePoints([]GameResult{game})
+}
+
+var f1Points = []int{25, 18, 15, 12, 10, 8, 6, 4, 2, 1}
+
+// F1Scoring scores the top ten finishers like a Formula 1 race, 25 points
+// for a win down to 1 for tenth
+func F1Scoring(game GameResult) map[string]int {
+	if len(game.Finishing) == 0 {
+		return map[string]int{game.Winner: f1Points[0]}
+	}
+	points := map[string]int{}
+	for position, name := range game.Finishing {
+		if position < len(f1Points) {
+			points[name] = f1Points[position]
+		}
+	}
+	return points
+}
+
+// ScorePoints totals the points scoring awards for each of games
+func ScorePoints(games []GameResult, scoring Scoring) map[string]int {
+	points := map[string]int{}
+	for _, game := range games {
+		for name, p := range scoring.Score(game) {
+			points[name] += p
+		}
+	}
+	return points
+}
+
+// sortByPoints orders league by most points, then most wins, then name so
+// that ties always come out the same way
+func sortByPoints(league League, points map[string]int) League {
+	sort.SliceStable(league, func(i, j int) bool {
+		a, b := league[i], league[j]
+		if points[a.Name] != points[b.Name] {
+			return points[a.Name] > points[b.Name]
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return a.Name < b.Name
+	})
+	return league
+}
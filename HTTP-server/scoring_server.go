@@ -0,0 +1,49 @@
+package poker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// scoringRequest is the body of GET and PUT /scoring
+type scoringRequest struct {
+	Scoring string `json:"scoring"`
+}
+
+// scoringHandler shows the scoring in use on GET and changes the League's
+// scoring on PUT
+func (p *PlayerServer) scoringHandler(w http.ResponseWriter, r *http.Request) {
+	if p.scoring == nil {
+		http.Error(w, "this store does not support choosing a scoring", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		scoring, err := p.scoring.GetScoring(r.Context())
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, scoringRequest{scoring})
+	case http.MethodPut:
+		var body scoringRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "could not parse scoring: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		err := p.scoring.SetScoring(r.Context(), body.Scoring)
+		if errors.Is(err, ErrUnknownScoring) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, body)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
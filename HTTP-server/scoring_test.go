@@ -0,0 +1,189 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScorePoints(t *testing.T) {
+	t.Run("wins scores a point per win", func(t *testing.T) {
+		got := poker.ScorePoints([]poker.GameResult{
+			{Winner: "Cleo", Finishing: []string{"Cleo", "Chris", "Floyd"}},
+			{Winner: "Cleo"},
+		}, poker.ScoringFunc(poker.WinsScoring))
+		want := map[string]int{"Cleo": 2}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got points %v, want %v", got, want)
+		}
+	})
+	t.Run("players beaten scores the same as LeaguePoints", func(t *testing.T) {
+		games := []poker.GameResult{
+			{Finishing: []string{"Cleo", "Chris", "Floyd"}},
+			{Winner: "Cleo", Players: 6},
+		}
+		got := poker.ScorePoints(games, poker.ScoringFunc(poker.PlayersBeatenScoring))
+		want := poker.LeaguePoints(games)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got points %v, want %v", got, want)
+		}
+	})
+	t.Run("f1 scores the top ten finishers", func(t *testing.T) {
+		finishing := []string{"A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K"}
+		got := poker.ScorePoints([]poker.GameResult{
+			{Finishing: finishing},
+			{Winner: "K"},
+		}, poker.ScoringFunc(poker.F1Scoring))
+		want := map[string]int{"A": 25, "B": 18, "C": 15, "D": 12, "E": 10, "F": 8, "G": 6, "H": 4, "I": 2, "J": 1, "K": 25}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got points %v, want %v", got, want)
+		}
+	})
+}
+
+func TestScoringByName(t *testing.T) {
+	for _, name := range []string{"", "wins", "players-beaten", "f1"} {
+		if _, err := poker.ScoringByName(name); err != nil {
+			t.Errorf("expected scoring %q to exist, got %v", name, err)
+		}
+	}
+	_, err := poker.ScoringByName("golf")
+	assertError(t, err, poker.ErrUnknownScoring)
+}
+
+func TestFileSystemStoreScoring(t *testing.T) {
+	t.Run("league is sorted by points then wins then name", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		assertNoError(t, store.SetScoring(context.Background(), "f1"))
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Floyd", "Cleo", "Chris"}})
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Cleo", "Floyd", "Chris"}})
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Chris", "Alice"}})
+
+		assertLeague(t, store.GetLeague(), poker.League{
+			{"Chris", 1},
+			{"Cleo", 1},
+			{"Floyd", 1},
+		})
+	})
+	t.Run("scores players beaten by default but keeps the league ordered by wins", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		mustRecordGame(t, store, poker.GameResult{Winner: "Chris", Players: 8})
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Cleo", "Floyd"}})
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Cleo", "Floyd"}})
+
+		scoring, err := store.GetScoring(context.Background())
+		assertNoError(t, err)
+		if scoring != "players-beaten" {
+			t.Errorf("got scoring %q, want players-beaten", scoring)
+		}
+		points, err := store.GetPoints(context.Background())
+		assertNoError(t, err)
+		if want := map[string]int{"Chris": 7, "Cleo": 2, "Floyd": 0}; !reflect.DeepEqual(points, want) {
+			t.Errorf("got points %v, want %v", points, want)
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Cleo", 2}, {"Chris", 1}})
+	})
+	t.Run("carried over wins score as winner only games", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, `[{"Name": "Cleo", "Wins": 2}]`)
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		assertNoError(t, store.SetScoring(context.Background(), "f1"))
+		points, err := store.GetPoints(context.Background())
+		assertNoError(t, err)
+		if points["Cleo"] != 50 {
+			t.Errorf("got %d points for Cleo, want 50", points["Cleo"])
+		}
+	})
+	t.Run("a season's scoring overrides the league's and sticks to its archive", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		summer := mustStartSeason(t, store, poker.Season{Name: "Summer", Start: friday, End: saturday, Scoring: "players-beaten"})
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Chris", "Cleo", "Floyd"}, Time: friday.Add(time.Hour)})
+		mustStartSeason(t, store, poker.Season{Name: "Autumn", Start: time.Now().Add(-time.Hour)})
+		assertNoError(t, store.SetScoring(context.Background(), "f1"))
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Floyd", "Cleo"}})
+
+		scoring, err := store.GetScoring(context.Background())
+		assertNoError(t, err)
+		if scoring != "f1" {
+			t.Errorf("got scoring %q, want f1", scoring)
+		}
+		points, err := store.GetPoints(context.Background())
+		assertNoError(t, err)
+		if want := map[string]int{"Floyd": 25, "Cleo": 18}; !reflect.DeepEqual(points, want) {
+			t.Errorf("got points %v, want %v", points, want)
+		}
+
+		league, err := store.GetSeasonLeague(context.Background(), summer.ID)
+		assertNoError(t, err)
+		assertLeague(t, league, poker.League{{"Chris", 1}})
+	})
+	t.Run("rejects an unknown scoring", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+
+		assertError(t, store.SetScoring(context.Background(), "golf"), poker.ErrUnknownScoring)
+		_, err = store.StartSeason(context.Background(), poker.Season{Name: "Q4", Start: friday, Scoring: "golf"})
+		assertError(t, err, poker.ErrUnknownScoring)
+	})
+}
+
+func TestScoringEndpoint(t *testing.T) {
+	t.Run("PUT /scoring changes how the league is scored", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Cleo", "Chris"}})
+		server := mustMakePlayerServer(t, store)
+
+		request, _ := http.NewRequest(http.MethodPut, "/scoring", strings.NewReader(`{"scoring": "f1"}`))
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assertStatus(t, response.Code, http.StatusOK)
+
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, newRequest(http.MethodGet, "/scoring"))
+		assertStatus(t, response.Code, http.StatusOK)
+		if got := strings.TrimSpace(response.Body.String()); got != `{"scoring":"f1"}` {
+			t.Errorf("got body %s, want f1 scoring", got)
+		}
+
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, newRequest(http.MethodGet, "/league?sort=points"))
+		standings := getRatedLeagueFromResponse(t, response)
+		if len(standings) != 1 || standings[0].Name != "Cleo" || standings[0].Points != 25 {
+			t.Errorf("got standings %+v", standings)
+		}
+	})
+	t.Run("PUT /scoring returns 400 on an unknown scoring", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &poker.StubPlayerStore{})
+		request, _ := http.NewRequest(http.MethodPut, "/scoring", strings.NewReader(`{"scoring": "golf"}`))
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		assertStatus(t, response.Code, http.StatusBadRequest)
+	})
+	t.Run("returns 501 when the store can't choose a scoring", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &legacyPlayerStore{})
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newRequest(http.MethodGet, "/scoring"))
+		assertStatus(t, response.Code, http.StatusNotImplemented)
+	})
+}
@@ -0,0 +1,50 @@
+package poker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrSeasonNotFound is returned when asked for a season that doesn't exist
+	ErrSeasonNotFound = errors.New("season not found")
+	// ErrSeasonOverlap is returned when a new season overlaps an existing one
+	ErrSeasonOverlap = errors.New("season overlaps an existing season")
+	// ErrInvalidSeason is returned for a season without a name or with an end before its start
+	ErrInvalidSeason = errors.New("season needs a name and a start before its end")
+)
+
+// Season is a time boxed league, a zero End means it runs until the next
+// season is started
+type Season struct {
+	ID    int       `json:"id"`
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end,omitempty"`
+	// Scoring names the Scoring used for this season, empty uses the League's
+	Scoring string `json:"scoring,omitempty"`
+}
+
+// Contains reports whether t falls within the season
+func (s Season) Contains(t time.Time) bool {
+	return !t.Before(s.Start) && (s.End.IsZero() || t.Before(s.End))
+}
+
+func (s Season) overlaps(other Season) bool {
+	startsBeforeOtherEnds := other.End.IsZero() || s.Start.Before(other.End)
+	endsAfterOtherStarts := s.End.IsZero() || s.End.After(other.Start)
+	return startsBeforeOtherEnds && endsAfterOtherStarts
+}
+
+// SeasonStore is implemented by stores that split their games into seasons.
+// Their GetLeague is scoped to the season running now, games are recorded
+// into the season running when they were played.
+type SeasonStore interface {
+	// StartSeason adds season, ending an open ended season that started
+	// before it, and fills in its ID
+	StartSeason(ctx context.Context, season Season) (Season, error)
+	GetSeasons(ctx context.Context) ([]Season, error)
+	// GetSeasonLeague returns the standings of a current or archived season
+	GetSeasonLeague(ctx context.Context, id int) (League, error)
+}
@@ -0,0 +1,91 @@
+package poker
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// seasonsHandler lists seasons on GET and starts a new one on POST
+func (p *PlayerServer) seasonsHandler(w http.ResponseWriter, r *http.Request) {
+	if p.seasons == nil {
+		http.Error(w, "this store does not keep seasons", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		seasons, err := p.seasons.GetSeasons(r.Context())
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, seasons)
+	case http.MethodPost:
+		var season Season
+		if err := json.NewDecoder(r.Body).Decode(&season); err != nil {
+			http.Error(w, "could not parse season: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		season, err := p.seasons.StartSeason(r.Context(), season)
+		if err != nil {
+			seasonError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, season)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// seasonHandler serves /seasons/{id}/league, the standings of one season
+func (p *PlayerServer) seasonHandler(w http.ResponseWriter, r *http.Request) {
+	if p.seasons == nil {
+		http.Error(w, "this store does not keep seasons", http.StatusNotImplemented)
+		return
+	}
+
+	idText, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/seasons/"), "/")
+	id, err := strconv.Atoi(idText)
+	if err != nil || rest != "league" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	league, err := p.seasons.GetSeasonLeague(r.Context(), id)
+	if err != nil {
+		seasonError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, league)
+}
+
+func seasonError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUnknownScoring):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrSeasonNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, ErrInvalidSeason):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrSeasonOverlap):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		storeError(w, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("content-type", JsonContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("problem encoding response: %v", err)
+	}
+}
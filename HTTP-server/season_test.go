@@ -0,0 +1,166 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFileSystemStoreSeasons(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	newStore := func(t *testing.T) *poker.FileSystemPlayerStore {
+		database, cleanDatabase := createTempFile(t, `[{"Name": "Chris", "Wins": 33}]`)
+		t.Cleanup(cleanDatabase)
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+		return store
+	}
+
+	t.Run("without seasons the League is all time", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Cleo")
+		assertLeague(t, store.GetLeague(), []poker.Player{{"Chris", 33}, {"Cleo", 1}})
+	})
+	t.Run("the League and scores are scoped to the current season", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Cleo")
+		mustStartSeason(t, store, poker.Season{Name: "Q4", Start: now.Add(-time.Hour)})
+		store.RecordWin("Pepper")
+		store.RecordWin("Pepper")
+
+		assertLeague(t, store.GetLeague(), []poker.Player{{"Pepper", 2}})
+
+		score, err := store.GetPlayerScoreContext(ctx, "Chris")
+		assertNoError(t, err)
+		assertScoreEquals(t, score, 0)
+		_, err = store.GetPlayerScoreContext(ctx, "Apollo")
+		assertError(t, err, poker.ErrPlayerNotFound)
+	})
+	t.Run("games are recorded into the season they were played in", func(t *testing.T) {
+		store := newStore(t)
+		q3 := mustStartSeason(t, store, poker.Season{Name: "Q3", Start: friday.AddDate(0, -3, 0), End: friday.AddDate(0, 0, -1)})
+		mustRecordGame(t, store, poker.GameResult{Winner: "Cleo", Time: friday.AddDate(0, -1, 0)})
+		mustRecordGame(t, store, poker.GameResult{Winner: "Floyd", Time: friday})
+
+		games, err := store.GetGames(ctx, poker.GameFilter{})
+		assertNoError(t, err)
+		if games[0].Season != q3.ID || games[1].Season != 0 {
+			t.Errorf("got seasons %d and %d, want %d and none", games[0].Season, games[1].Season, q3.ID)
+		}
+	})
+	t.Run("starting a season archives the open one", func(t *testing.T) {
+		store := newStore(t)
+		first := mustStartSeason(t, store, poker.Season{Name: "Q3", Start: now.Add(-2 * time.Hour)})
+		store.RecordWin("Cleo")
+		second := mustStartSeason(t, store, poker.Season{Name: "Q4", Start: now.Add(-time.Minute)})
+		store.RecordWin("Pepper")
+
+		seasons, err := store.GetSeasons(ctx)
+		assertNoError(t, err)
+		if !seasons[0].End.Equal(second.Start) {
+			t.Errorf("expected %q to end when %q started, got %v", first.Name, second.Name, seasons[0].End)
+		}
+
+		archived, err := store.GetSeasonLeague(ctx, first.ID)
+		assertNoError(t, err)
+		assertLeague(t, archived, []poker.Player{{"Cleo", 1}})
+		assertLeague(t, store.GetLeague(), []poker.Player{{"Pepper", 1}})
+	})
+	t.Run("rejects overlapping, invalid and unknown seasons", func(t *testing.T) {
+		store := newStore(t)
+		mustStartSeason(t, store, poker.Season{Name: "Q4", Start: friday, End: monday})
+
+		_, err := store.StartSeason(ctx, poker.Season{Name: "Clash", Start: saturday, End: monday.Add(time.Hour)})
+		assertError(t, err, poker.ErrSeasonOverlap)
+		_, err = store.StartSeason(ctx, poker.Season{Name: "Backwards", Start: monday.AddDate(0, 0, 7), End: monday})
+		assertError(t, err, poker.ErrInvalidSeason)
+		_, err = store.GetSeasonLeague(ctx, 42)
+		assertError(t, err, poker.ErrSeasonNotFound)
+	})
+}
+
+func TestSeasonsEndpoints(t *testing.T) {
+	t.Run("starts, lists and shows the League of seasons", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		server := mustMakePlayerServer(t, store)
+
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newStartSeasonRequest(t, poker.Season{Name: "Q4", Start: friday}))
+		assertStatus(t, response.Code, http.StatusCreated)
+		var started poker.Season
+		json.NewDecoder(response.Body).Decode(&started)
+
+		mustRecordGame(t, store, poker.GameResult{Winner: "Cleo", Time: saturday})
+
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, newRequest(http.MethodGet, "/seasons"))
+		assertStatus(t, response.Code, http.StatusOK)
+		var seasons []poker.Season
+		json.NewDecoder(response.Body).Decode(&seasons)
+		if len(seasons) != 1 || seasons[0].Name != "Q4" {
+			t.Errorf("got seasons %v, want Q4", seasons)
+		}
+
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, newRequest(http.MethodGet, fmt.Sprintf("/seasons/%d/league", started.ID)))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertLeague(t, getLeagueFromResponse(t, response.Body), []poker.Player{{"Cleo", 1}})
+	})
+	t.Run("maps season errors to statuses", func(t *testing.T) {
+		store := &poker.StubPlayerStore{Seasons: []poker.Season{{ID: 1, Name: "Q4", Start: friday, End: monday}}}
+		server := mustMakePlayerServer(t, store)
+
+		cases := map[string]struct {
+			request *http.Request
+			want    int
+		}{
+			"unknown season":     {newRequest(http.MethodGet, "/seasons/7/league"), http.StatusNotFound},
+			"bad season id":      {newRequest(http.MethodGet, "/seasons/q4/league"), http.StatusNotFound},
+			"overlapping season": {newStartSeasonRequest(t, poker.Season{Name: "Clash", Start: saturday}), http.StatusConflict},
+			"unnamed season":     {newStartSeasonRequest(t, poker.Season{Start: monday}), http.StatusBadRequest},
+		}
+		for name, c := range cases {
+			t.Run(name, func(t *testing.T) {
+				response := httptest.NewRecorder()
+				server.ServeHTTP(response, c.request)
+				assertStatus(t, response.Code, c.want)
+			})
+		}
+	})
+	t.Run("returns 501 when the store has no seasons", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &legacyPlayerStore{scores: map[string]int{}})
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newRequest(http.MethodGet, "/seasons"))
+		assertStatus(t, response.Code, http.StatusNotImplemented)
+	})
+}
+
+func mustStartSeason(t testing.TB, store poker.SeasonStore, season poker.Season) poker.Season {
+	t.Helper()
+	started, err := store.StartSeason(context.Background(), season)
+	if err != nil {
+		t.Fatalf("could not start season %v: %v", season, err)
+	}
+	return started
+}
+
+func newStartSeasonRequest(t testing.TB, season poker.Season) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(season)
+	assertNoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, "/seasons", bytes.NewReader(body))
+	return req
+}
+
+func newRequest(method, url string) *http.Request {
+	req, _ := http.NewRequest(method, url, nil)
+	return req
+}
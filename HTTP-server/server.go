@@ -1,14 +1,19 @@
 package poker
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const JsonContentType = "application/json"
@@ -21,11 +26,52 @@ type PlayerStore interface {
 }
 
 type PlayerServer struct {
-	store PlayerStore
+	store PlayerStoreV2
+	// the optional stores are nil unless store supports them
+	games      GameStore
+	seasons    SeasonStore
+	scoring    ScoringStore
+	editor     PlayerEditor
+	tombstones TombstoneStore
+	audit      AuditStore
+	undo       UndoStore
+	data       LeagueDataStore
+	// adminToken is the bearer token admin only routes need, they're
+	// forbidden to everyone when it's empty
+	adminToken string
+	// idempotency remembers the responses to retried requests, it's nil
+	// when idempotencyWindow or idempotencyLimit is zero or less
+	idempotency       *idempotencyCache
+	idempotencyWindow time.Duration
+	idempotencyLimit  int
+	// newGame makes the PokerGame a websocket client plays, its blinds
+	// sent by alerter
+	newGame func(alerter BlindAlerter) *PokerGame
+	// rooms holds up to roomLimit rooms, each closed once it's been empty
+	// for roomIdleTimeout
+	rooms           *gameRooms
+	roomLimit       int
+	roomIdleTimeout time.Duration
+	// heartbeat is how often websockets are pinged, sessions is nil when
+	// resumeWindow is zero or less and holds up to sessionLimit
+	heartbeat    time.Duration
+	resumeWindow time.Duration
+	sessionLimit int
+	sessions     *wsSessions
 	http.Handler
 	template *template.Template
 }
 
+// ServerOption configures a PlayerServer
+type ServerOption func(p *PlayerServer)
+
+// WithAdminToken lets requests bearing token use admin only routes
+func WithAdminToken(token string) ServerOption {
+	return func(p *PlayerServer) {
+		p.adminToken = token
+	}
+}
+
 type Player struct {
 	Name string
 	Wins int
@@ -43,8 +89,19 @@ var wsUpgrader = websocket.Upgrader{
 	WriteBufferSize: 1024,
 }
 
-func NewPlayerServer(store PlayerStore) (*PlayerServer, error) {
-	p := new(PlayerServer)
+func NewPlayerServer(store PlayerStore, options ...ServerOption) (*PlayerServer, error) {
+	p := &PlayerServer{
+		idempotencyWindow: DefaultIdempotencyWindow,
+		idempotencyLimit:  DefaultIdempotencyLimit,
+		heartbeat:         DefaultHeartbeat,
+		resumeWindow:      DefaultResumeWindow,
+		sessionLimit:      DefaultSessionLimit,
+		roomLimit:         DefaultRoomLimit,
+		roomIdleTimeout:   DefaultRoomIdleTimeout,
+	}
+	for _, option := range options {
+		option(p)
+	}
 
 	tmpl, err := template.ParseFS(gameTemplates, htmlTemplatePath)
 
@@ -53,70 +110,413 @@ func NewPlayerServer(store PlayerStore) (*PlayerServer, error) {
 	}
 
 	p.template = tmpl
-	p.store = store
+	if p.idempotencyWindow > 0 && p.idempotencyLimit > 0 {
+		p.idempotency = newIdempotencyCache(p.idempotencyWindow, p.idempotencyLimit)
+	}
+	if p.resumeWindow > 0 {
+		p.sessions = newWSSessions(p.resumeWindow, p.sessionLimit)
+	}
+	p.store = AdaptPlayerStore(store)
+	p.newGame = func(alerter BlindAlerter) *PokerGame {
+		game := NewPokerGame(alerter, store)
+		game.Source = SourceWeb
+		return game
+	}
+	p.rooms = newGameRooms(p.newGame, p.roomLimit, p.roomIdleTimeout)
+	p.games, _ = store.(GameStore)
+	p.seasons, _ = store.(SeasonStore)
+	p.scoring, _ = store.(ScoringStore)
+	p.editor, _ = store.(PlayerEditor)
+	p.tombstones, _ = store.(TombstoneStore)
+	p.audit, _ = store.(AuditStore)
+	p.undo, _ = store.(UndoStore)
+	p.data, _ = store.(LeagueDataStore)
 	router := http.NewServeMux()
 	router.Handle("/league", http.HandlerFunc(p.leagueHandler))
-	router.Handle("/players/", http.HandlerFunc(p.playersHandler))
+	router.Handle("/players/", p.idempotent(p.playersHandler))
+	router.Handle("/merge", http.HandlerFunc(p.mergeHandler))
+	router.Handle("/deleted", http.HandlerFunc(p.deletedHandler))
+	router.Handle("/restore/", http.HandlerFunc(p.restoreHandler))
+	router.Handle("/audit", http.HandlerFunc(p.auditHandler))
+	router.Handle("/games", p.idempotent(p.gamesHandler))
+	router.Handle("/undo", http.HandlerFunc(p.undoHandler))
+	router.Handle("/undone", http.HandlerFunc(p.undoneHandler))
+	router.Handle("/redo/", http.HandlerFunc(p.redoHandler))
+	router.Handle("/import", http.HandlerFunc(p.importHandler))
+	router.Handle("/export", http.HandlerFunc(p.exportHandler))
+	router.Handle("/seasons", http.HandlerFunc(p.seasonsHandler))
+	router.Handle("/seasons/", http.HandlerFunc(p.seasonHandler))
+	router.Handle("/scoring", http.HandlerFunc(p.scoringHandler))
 	router.Handle("/game", http.HandlerFunc(p.game))
+	router.Handle("/clock", http.HandlerFunc(p.clock))
+	router.Handle("/rooms", http.HandlerFunc(p.roomsHandler))
+	router.Handle("/rooms/", http.HandlerFunc(p.roomHandler))
 	router.Handle("/ws", http.HandlerFunc(p.webSocket))
-	p.Handler = router
+	p.Handler = p.actorHandler(router)
 
 	return p, nil
 }
 
+// leagueHandler returns the League, with everyone's points and Elo rating
+// when the store keeps a history of games. ?sort=wins, ?sort=points or
+// ?sort=rating reorder it, otherwise it keeps the store's order.
 func (p *PlayerServer) leagueHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("content-type", JsonContentType)
-	err := json.NewEncoder(w).Encode(p.store.GetLeague())
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != "" && sortBy != "wins" && sortBy != "points" && sortBy != "rating" {
+		http.Error(w, "sort must be wins, points or rating", http.StatusBadRequest)
+		return
+	}
+
+	league, err := p.store.GetLeagueContext(r.Context())
 	if err != nil {
-		log.Printf("league handler encountered an error: %v", err)
+		storeError(w, err)
+		return
 	}
-	w.WriteHeader(http.StatusOK)
+
+	if p.games == nil {
+		if sortBy == "points" || sortBy == "rating" {
+			http.Error(w, "this store does not keep the games needed for "+sortBy, http.StatusNotImplemented)
+			return
+		}
+		writeJSON(w, http.StatusOK, league)
+		return
+	}
+
+	games, err := p.games.GetGames(r.Context(), GameFilter{})
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	points, err := p.leaguePoints(r.Context(), games)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+
+	standings := Standings(league, points, EloRatings(games))
+	if sortBy != "" {
+		sortStandings(standings, sortBy)
+	}
+	writeJSON(w, http.StatusOK, standings)
+}
+
+// leaguePoints asks the store for everyone's points, scoring the games of
+// the current season by the players each beat when it can't say
+func (p *PlayerServer) leaguePoints(ctx context.Context, games []GameResult) (map[string]int, error) {
+	if p.scoring != nil {
+		return p.scoring.GetPoints(ctx)
+	}
+	season, err := p.currentSeason(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return LeaguePoints(FilterGames(games, GameFilter{Season: season.ID})), nil
+}
+
+// currentSeason is the season running now, or the zero Season when there
+// isn't one or the store doesn't keep seasons
+func (p *PlayerServer) currentSeason(ctx context.Context) (Season, error) {
+	if p.seasons == nil {
+		return Season{}, nil
+	}
+	seasons, err := p.seasons.GetSeasons(ctx)
+	if err != nil {
+		return Season{}, err
+	}
+	now := time.Now()
+	for _, season := range seasons {
+		if season.Contains(now) {
+			return season, nil
+		}
+	}
+	return Season{}, nil
 }
 
 func (p *PlayerServer) playersHandler(w http.ResponseWriter, r *http.Request) {
 	player := strings.TrimPrefix(r.URL.Path, "/players/")
 	switch r.Method {
 	case http.MethodPost:
-		p.processWin(w, player)
+		p.processWin(w, r, player)
 	case http.MethodGet:
-		p.showScore(w, player)
+		p.showScore(w, r, player)
 	case http.MethodDelete:
-		p.deletePlayer(w, player)
+		p.deletePlayer(w, r, player)
+	case http.MethodPatch:
+		p.renamePlayer(w, r, player)
 	}
 }
 
 func (p *PlayerServer) game(w http.ResponseWriter, r *http.Request) {
-	err := p.template.Execute(w, nil)
+	p.page(w, "game.html")
+}
+
+// clock is a full screen blind clock following the game in ?room=, for a TV
+// everyone at the table can see
+func (p *PlayerServer) clock(w http.ResponseWriter, r *http.Request) {
+	p.page(w, "clock.html")
+}
+
+func (p *PlayerServer) page(w http.ResponseWriter, name string) {
+	err := p.template.ExecuteTemplate(w, name, nil)
 	if err != nil {
 		log.Printf("template encountered an error: %v", err)
 	}
 }
 
-func (p *PlayerServer) webSocket(w http.ResponseWriter, r *http.Request) {
-	conn, _ := wsUpgrader.Upgrade(w, r, nil)
-	_, winnerMessage, _ := conn.ReadMessage()
-	p.store.RecordWin(string(winnerMessage))
+// deletePlayer deletes a player, a soft delete when the store keeps
+// tombstones. ?purge=true removes them for good and is admin only.
+func (p *PlayerServer) deletePlayer(w http.ResponseWriter, r *http.Request, name string) {
+	if r.URL.Query().Get("purge") == "true" {
+		p.purgePlayer(w, r, name)
+		return
+	}
+	if err := p.store.DeletePlayerContext(r.Context(), name); err != nil {
+		storeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *PlayerServer) showScore(w http.ResponseWriter, r *http.Request, player string) {
+	score, err := p.store.GetPlayerScoreContext(r.Context(), player)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+
+	fmt.Fprint(w, score)
+}
+
+func (p *PlayerServer) processWin(w http.ResponseWriter, r *http.Request, player string) {
+	game := GameResult{Winner: player, Source: SourceAPI}
+	if players := r.URL.Query().Get("players"); players != "" {
+		n, err := strconv.Atoi(players)
+		if err != nil || n < 1 {
+			http.Error(w, "players must be a positive number", http.StatusBadRequest)
+			return
+		}
+		game.Players = n
+	}
+
+	if err := p.recordWin(r.Context(), game); err != nil {
+		storeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// recordWin keeps the whole game when the store has a history, otherwise
+// just the win
+func (p *PlayerServer) recordWin(ctx context.Context, game GameResult) error {
+	if p.games == nil {
+		winner := game.Winner
+		if winner == "" && len(game.Finishing) > 0 {
+			winner = game.Finishing[0]
+		}
+		return p.store.RecordWinContext(ctx, winner)
+	}
+	_, err := p.games.RecordGame(ctx, game)
+	return err
+}
+
+// gamesHandler lists the game history on GET, filtered by the optional
+// player, season, from and to query parameters. from and to take RFC 3339
+// times or dates, a date given for to includes that whole day. POST records
+// a game, with its finishing order, from a JSON GameResult.
+func (p *PlayerServer) gamesHandler(w http.ResponseWriter, r *http.Request) {
+	if p.games == nil {
+		http.Error(w, "this store does not keep a history of games", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		p.listGames(w, r)
+	case http.MethodPost:
+		p.postGame(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
 }
 
-func (p *PlayerServer) deletePlayer(w http.ResponseWriter, name string) {
-	if p.store.GetPlayerScore(name) != 0 {
-		p.store.DeletePlayer(name)
-		w.WriteHeader(http.StatusOK)
+// undoHandler takes back the last recorded game on POST, or the last n with
+// ?n=, and returns the games undone. Undoing more than one game at a time is
+// admin only.
+func (p *PlayerServer) undoHandler(w http.ResponseWriter, r *http.Request) {
+	if p.undo == nil {
+		http.Error(w, "this store can't undo games", http.StatusNotImplemented)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	n := 1
+	if count := r.URL.Query().Get("n"); count != "" {
+		var err error
+		if n, err = strconv.Atoi(count); err != nil {
+			http.Error(w, "n must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+	if n > 1 && !p.isAdmin(r) {
+		http.Error(w, "undoing more than one game is admin only", http.StatusForbidden)
+		return
+	}
+
+	undone, err := p.undo.UndoGames(r.Context(), n)
+	switch {
+	case errors.Is(err, ErrInvalidUndo):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrNothingToUndo):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		storeError(w, err)
+	default:
+		writeJSON(w, http.StatusOK, undone)
 	}
-	w.WriteHeader(http.StatusNotFound)
 }
 
-func (p *PlayerServer) showScore(w http.ResponseWriter, player string) {
-	score := p.store.GetPlayerScore(player)
+// undoneHandler lists the undos that can be redone
+func (p *PlayerServer) undoneHandler(w http.ResponseWriter, r *http.Request) {
+	if p.undo == nil {
+		http.Error(w, "this store can't undo games", http.StatusNotImplemented)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	undone, err := p.undo.GetUndone(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, undone)
+}
 
-	if score == 0 {
+// redoHandler puts back the games of an undo on POST /redo/{id} and returns
+// them. Like restoring a deleted player, it's admin only.
+func (p *PlayerServer) redoHandler(w http.ResponseWriter, r *http.Request) {
+	if p.undo == nil {
+		http.Error(w, "this store can't undo games", http.StatusNotImplemented)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !p.isAdmin(r) {
+		http.Error(w, "redoing games is admin only", http.StatusForbidden)
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/redo/"))
+	if err != nil {
+		http.Error(w, "undo id must be a number", http.StatusBadRequest)
+		return
+	}
+
+	redone, err := p.undo.RedoGames(r.Context(), id)
+	switch {
+	case errors.Is(err, ErrUndoNotFound):
 		w.WriteHeader(http.StatusNotFound)
+	case err != nil:
+		storeError(w, err)
+	default:
+		writeJSON(w, http.StatusOK, redone)
 	}
+}
 
-	fmt.Fprint(w, score)
+func (p *PlayerServer) postGame(w http.ResponseWriter, r *http.Request) {
+	var game GameResult
+	if err := json.NewDecoder(r.Body).Decode(&game); err != nil {
+		http.Error(w, "could not parse game: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	game.ID = 0
+	game.Season = 0
+	if game.Source == "" {
+		game.Source = SourceAPI
+	}
+
+	recorded, err := p.games.RecordGame(r.Context(), game)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, recorded)
 }
 
-func (p *PlayerServer) processWin(w http.ResponseWriter, player string) {
-	p.store.RecordWin(player)
-	w.WriteHeader(http.StatusAccepted)
+func (p *PlayerServer) listGames(w http.ResponseWriter, r *http.Request) {
+	filter, err := gameFilterFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	games, err := p.games.GetGames(r.Context(), filter)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, games)
+}
+
+func gameFilterFromQuery(query url.Values) (GameFilter, error) {
+	filter := GameFilter{Player: query.Get("player")}
+
+	if season := query.Get("season"); season != "" {
+		id, err := strconv.Atoi(season)
+		if err != nil {
+			return filter, fmt.Errorf("bad season: %v", err)
+		}
+		filter.Season = id
+	}
+
+	if from := query.Get("from"); from != "" {
+		t, _, err := parseQueryTime(from)
+		if err != nil {
+			return filter, fmt.Errorf("bad from: %v", err)
+		}
+		filter.From = t
+	}
+	if to := query.Get("to"); to != "" {
+		t, isDate, err := parseQueryTime(to)
+		if err != nil {
+			return filter, fmt.Errorf("bad to: %v", err)
+		}
+		if isDate {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = t
+	}
+	return filter, nil
+}
+
+func parseQueryTime(value string) (t time.Time, isDate bool, err error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+// storeError maps an error from the PlayerStore onto a response, anything
+// other than a missing player is the store's fault rather than the client's
+func storeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrPlayerNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, ErrInvalidGame) || errors.Is(err, ErrInvalidPlayerName) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, ErrPlayerExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	log.Printf("player store encountered an error: %v", err)
+	w.WriteHeader(http.StatusInternalServerError)
 }
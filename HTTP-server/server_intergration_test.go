@@ -4,14 +4,35 @@ import (
 	poker "HTTP-server"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 )
 
 func TestRecordingWinsAndRetrievingThem(t *testing.T) {
-	database, cleanDatabase := createTempFile(t, "[]")
-	defer cleanDatabase()
-	store, err := poker.NewFileSystemPlayerStore(database)
-	assertNoError(t, err)
+	t.Run("file system store", func(t *testing.T) {
+		database, cleanDatabase := createTempFile(t, "[]")
+		defer cleanDatabase()
+		store, err := poker.NewFileSystemPlayerStore(database)
+		assertNoError(t, err)
+		assertRecordingWinsAndRetrievingThem(t, store)
+	})
+
+	t.Run("log store", func(t *testing.T) {
+		assertRecordingWinsAndRetrievingThem(t, mustMakeLogPlayerStore(t, createLogFile(t, "")))
+	})
+
+	t.Run("sqlite store", func(t *testing.T) {
+		store := mustMakeSQLitePlayerStore(t, filepath.Join(t.TempDir(), "game.db"))
+		assertRecordingWinsAndRetrievingThem(t, store)
+	})
+
+	t.Run("postgres store", func(t *testing.T) {
+		assertRecordingWinsAndRetrievingThem(t, mustMakePostgresPlayerStore(t))
+	})
+}
+
+func assertRecordingWinsAndRetrievingThem(t *testing.T, store poker.PlayerStore) {
+	t.Helper()
 	server, _ := poker.NewPlayerServer(store)
 	player := "Pepper"
 
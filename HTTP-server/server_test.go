@@ -2,6 +2,7 @@ package poker_test
 
 import (
 	poker "HTTP-server"
+	"errors"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"io"
@@ -111,7 +112,7 @@ func TestLeague(t *testing.T) {
 			{"Chris", 20},
 			{"Tits", 14},
 		}
-		store := poker.StubPlayerStore{nil, nil, wantedLeague}
+		store := poker.StubPlayerStore{League: wantedLeague}
 		server := mustMakePlayerServer(t, &store)
 		request := newLeagueRequest()
 		response := httptest.NewRecorder()
@@ -123,6 +124,55 @@ func TestLeague(t *testing.T) {
 	})
 }
 
+func TestStoreErrors(t *testing.T) {
+	store := &poker.StubPlayerStore{
+		Scores: map[string]int{"Pepper": 20},
+		Err:    errors.New("disk on fire"),
+	}
+	server := mustMakePlayerServer(t, store)
+
+	requests := map[string]*http.Request{
+		"GET score":  newGetScoreRequest("Pepper"),
+		"POST win":   newPostWinRequest("Pepper"),
+		"DELETE":     newDeletePlayerRequest("Pepper"),
+		"GET league": newLeagueRequest(),
+	}
+	for name, request := range requests {
+		t.Run(name+" returns 500 when the store fails", func(t *testing.T) {
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+			assertStatus(t, response.Code, http.StatusInternalServerError)
+		})
+	}
+}
+
+func TestLegacyPlayerStore(t *testing.T) {
+	store := &legacyPlayerStore{scores: map[string]int{"Pepper": 20}}
+	server := mustMakePlayerServer(t, store)
+
+	t.Run("GET returns the score from a PlayerStore without errors", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newGetScoreRequest("Pepper"))
+		assertStatus(t, response.Code, http.StatusOK)
+		assertResponseBody(t, response.Body.String(), "20")
+	})
+	t.Run("GET treats a score of 0 as a missing player", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, newGetScoreRequest("Apollo"))
+		assertStatus(t, response.Code, http.StatusNotFound)
+	})
+}
+
+// legacyPlayerStore only implements the original PlayerStore interface
+type legacyPlayerStore struct {
+	scores map[string]int
+}
+
+func (l *legacyPlayerStore) GetPlayerScore(name string) int { return l.scores[name] }
+func (l *legacyPlayerStore) RecordWin(name string)          { l.scores[name]++ }
+func (l *legacyPlayerStore) GetLeague() poker.League        { return nil }
+func (l *legacyPlayerStore) DeletePlayer(name string)       { delete(l.scores, name) }
+
 func TestWebGame(t *testing.T) {
 	t.Run("GET /game returns status 200", func(t *testing.T) {
 		server := mustMakePlayerServer(t, &poker.StubPlayerStore{})
@@ -132,6 +182,17 @@ func TestWebGame(t *testing.T) {
 		server.ServeHTTP(response, request)
 		assertStatus(t, response.Code, http.StatusOK)
 	})
+	t.Run("GET /clock returns the tournament clock", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &poker.StubPlayerStore{})
+		request, _ := http.NewRequest(http.MethodGet, "/clock", nil)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+		assertStatus(t, response.Code, http.StatusOK)
+		if !strings.Contains(response.Body.String(), "<title>Tournament clock</title>") {
+			t.Errorf("got %q, want the clock page", response.Body.String())
+		}
+	})
 	t.Run("message sent from websocket is the winner of the game", func(t *testing.T) {
 		store := &poker.StubPlayerStore{}
 		winner := "Cleo"
@@ -181,6 +242,11 @@ func newPostWinRequest(name string) *http.Request {
 	return req
 }
 
+func newDeletePlayerRequest(name string) *http.Request {
+	req, _ := http.NewRequest(http.MethodDelete, "/players/"+name, nil)
+	return req
+}
+
 func newGetScoreRequest(name string) *http.Request {
 	req, _ := http.NewRequest(http.MethodGet, "/players/"+name, nil)
 	return req
@@ -220,6 +286,44 @@ func mustMakePlayerServer(t *testing.T, store poker.PlayerStore) *poker.PlayerSe
 	return server
 }
 
+// testAdminToken is the admin token of servers made by mustMakeFileSystemServer
+const testAdminToken = "let-me-in"
+
+// mustMakeFileSystemStore opens a FileSystemPlayerStore holding data, its
+// file is removed once the test is done
+func mustMakeFileSystemStore(t *testing.T, data string) *poker.FileSystemPlayerStore {
+	t.Helper()
+	database, cleanDatabase := createTempFile(t, data)
+	t.Cleanup(cleanDatabase)
+	store, err := poker.NewFileSystemPlayerStore(database)
+	assertNoError(t, err)
+	return store
+}
+
+// mustMakeFileSystemServer serves a FileSystemPlayerStore holding data, with
+// testAdminToken as its admin token
+func mustMakeFileSystemServer(t *testing.T, data string) (*poker.PlayerServer, *poker.FileSystemPlayerStore) {
+	t.Helper()
+	store := mustMakeFileSystemStore(t, data)
+	server, err := poker.NewPlayerServer(store, poker.WithAdminToken(testAdminToken))
+	if err != nil {
+		t.Fatalf("could not create player server: %v", err)
+	}
+	return server, store
+}
+
+func serve(server http.Handler, request *http.Request) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	return response
+}
+
+// asAdmin makes request bear testAdminToken
+func asAdmin(request *http.Request) *http.Request {
+	request.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return request
+}
+
 func mustDialWS(t *testing.T, url string) *websocket.Conn {
 	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
 
@@ -0,0 +1,207 @@
+package poker
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteMigrations are applied in order, each one exactly once, and the
+// number applied is tracked with SQLite's user_version pragma
+var sqliteMigrations = []func(tx *sql.Tx) error{
+	execMigration(`CREATE TABLE players (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		wins INTEGER NOT NULL DEFAULT 0
+	)`),
+	execMigration(`CREATE UNIQUE INDEX idx_players_name ON players (name)`),
+	migrateSQLitePlayerKeys,
+}
+
+func execMigration(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// migrateSQLitePlayerKeys looks players up by PlayerKey rather than name,
+// merging the wins of players who only differed by case or spacing into
+// whichever was recorded first
+func migrateSQLitePlayerKeys(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE players ADD COLUMN key TEXT NOT NULL DEFAULT ''`); err != nil {
+		return err
+	}
+
+	type row struct {
+		id   int64
+		name string
+		wins int
+	}
+	var rows []row
+	result, err := tx.Query(`SELECT id, name, wins FROM players ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	for result.Next() {
+		var r row
+		if err := result.Scan(&r.id, &r.name, &r.wins); err != nil {
+			result.Close()
+			return err
+		}
+		rows = append(rows, r)
+	}
+	result.Close()
+	if err := result.Err(); err != nil {
+		return err
+	}
+
+	firsts := map[string]int64{}
+	for _, r := range rows {
+		key := PlayerKey(r.name)
+		first, ok := firsts[key]
+		if !ok {
+			firsts[key] = r.id
+			if _, err := tx.Exec(`UPDATE players SET key = ? WHERE id = ?`, key, r.id); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE players SET wins = wins + ? WHERE id = ?`, r.wins, first); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM players WHERE id = ?`, r.id); err != nil {
+			return err
+		}
+	}
+
+	// a name index would fail inserts before the upsert on key could run
+	_, err = tx.Exec(`DROP INDEX idx_players_name;
+		CREATE UNIQUE INDEX idx_players_key ON players (key)`)
+	return err
+}
+
+// SQLitePlayerStore keeps the League in a single file SQLite database
+type SQLitePlayerStore struct {
+	db *sql.DB
+}
+
+func SQLitePlayerStoreFromFile(path string) (*SQLitePlayerStore, func(), error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, nil, fmt.Errorf("problem opening sqlite database %s, %v", path, err)
+	}
+	// a single connection serialises writers and keeps the file handle shared
+	db.SetMaxOpenConns(1)
+
+	closeFunc := func() {
+		db.Close()
+	}
+
+	store, err := NewSQLitePlayerStore(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("problem creating sqlite player store, %v", err)
+	}
+	return store, closeFunc, nil
+}
+
+func NewSQLitePlayerStore(db *sql.DB) (*SQLitePlayerStore, error) {
+	if err := migrateSQLite(db); err != nil {
+		return nil, err
+	}
+	return &SQLitePlayerStore{db: db}, nil
+}
+
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("problem reading schema version, %v", err)
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("problem starting migration %d, %v", i+1, err)
+		}
+		if err := sqliteMigrations[i](tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("problem applying migration %d, %v", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("problem recording migration %d, %v", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("problem committing migration %d, %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLitePlayerStore) GetLeague() League {
+	rows, err := s.db.Query(`SELECT name, wins FROM players ORDER BY wins DESC, name`)
+	if err != nil {
+		log.Printf("problem querying league, %v", err)
+		return nil
+	}
+	defer rows.Close()
+
+	league := League{}
+	for rows.Next() {
+		var player Player
+		if err := rows.Scan(&player.Name, &player.Wins); err != nil {
+			log.Printf("problem scanning player, %v", err)
+			return nil
+		}
+		league = append(league, player)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("problem reading league, %v", err)
+	}
+	return league
+}
+
+func (s *SQLitePlayerStore) GetPlayerScore(name string) int {
+	var wins int
+	err := s.db.QueryRow(`SELECT wins FROM players WHERE key = ?`, PlayerKey(name)).Scan(&wins)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("problem getting score for %s, %v", name, err)
+	}
+	return wins
+}
+
+func (s *SQLitePlayerStore) RecordWin(name string) {
+	err := s.inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO players (name, key, wins) VALUES (?, ?, 1)
+			ON CONFLICT (key) DO UPDATE SET wins = wins + 1`, CleanPlayerName(name), PlayerKey(name))
+		return err
+	})
+	if err != nil {
+		log.Printf("problem recording win for %s, %v", name, err)
+	}
+}
+
+func (s *SQLitePlayerStore) DeletePlayer(name string) {
+	err := s.inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM players WHERE key = ?`, PlayerKey(name))
+		return err
+	})
+	if err != nil {
+		log.Printf("problem deleting player %s, %v", name, err)
+	}
+}
+
+func (s *SQLitePlayerStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,71 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	t.Run("get League sorted by wins", func(t *testing.T) {
+		store := mustMakeSQLitePlayerStore(t, filepath.Join(t.TempDir(), "game.db"))
+		store.RecordWin("Cleo")
+		store.RecordWin("Chris")
+		store.RecordWin("Chris")
+
+		got := store.GetLeague()
+		want := poker.League{
+			{"Chris", 2},
+			{"Cleo", 1},
+		}
+		assertLeague(t, got, want)
+	})
+	t.Run("get score of unknown player is 0", func(t *testing.T) {
+		store := mustMakeSQLitePlayerStore(t, filepath.Join(t.TempDir(), "game.db"))
+		assertScoreEquals(t, store.GetPlayerScore("Apollo"), 0)
+	})
+	t.Run("delete player", func(t *testing.T) {
+		store := mustMakeSQLitePlayerStore(t, filepath.Join(t.TempDir(), "game.db"))
+		store.RecordWin("Pepper")
+		store.DeletePlayer("Pepper")
+		assertScoreEquals(t, store.GetPlayerScore("Pepper"), 0)
+		assertLeague(t, store.GetLeague(), poker.League{})
+	})
+	t.Run("wins survive reopening the database", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "game.db")
+		store, closeFunc, err := poker.SQLitePlayerStoreFromFile(path)
+		assertNoError(t, err)
+		store.RecordWin("Pepper")
+		store.RecordWin("Pepper")
+		closeFunc()
+
+		reopened := mustMakeSQLitePlayerStore(t, path)
+		assertScoreEquals(t, reopened.GetPlayerScore("Pepper"), 2)
+	})
+	t.Run("migrating merges players told apart by case", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "game.db")
+		db, err := sql.Open("sqlite3", path)
+		assertNoError(t, err)
+		_, err = db.Exec(`CREATE TABLE players (id INTEGER PRIMARY KEY, name TEXT NOT NULL, wins INTEGER NOT NULL DEFAULT 0);
+			CREATE UNIQUE INDEX idx_players_name ON players (name);
+			INSERT INTO players (name, wins) VALUES ('Pepper', 3), ('Floyd', 1), ('pepper', 2);
+			PRAGMA user_version = 2`)
+		assertNoError(t, err)
+		db.Close()
+
+		store := mustMakeSQLitePlayerStore(t, path)
+		store.RecordWin("PEPPER")
+		assertLeague(t, store.GetLeague(), poker.League{{"Pepper", 6}, {"Floyd", 1}})
+	})
+}
+
+func mustMakeSQLitePlayerStore(t *testing.T, path string) *poker.SQLitePlayerStore {
+	t.Helper()
+	store, closeFunc, err := poker.SQLitePlayerStoreFromFile(path)
+	if err != nil {
+		t.Fatalf("could not create sqlite player store: %v", err)
+	}
+	t.Cleanup(closeFunc)
+	return store
+}
@@ -0,0 +1,48 @@
+package poker
+
+import (
+	"math"
+	"sort"
+)
+
+// Standing is a Player's place in the League along with their points and Elo rating
+type Standing struct {
+	Player
+	Points int
+	Rating int
+}
+
+// Standings pairs everyone in league with their points and rating, keeping
+// the order of league
+func Standings(league League, points map[string]int, ratings map[string]float64) []Standing {
+	standings := make([]Standing, len(league))
+	for i, player := range league {
+		rating, ok := ratings[player.Name]
+		if !ok {
+			rating = InitialRating
+		}
+		standings[i] = Standing{player, points[player.Name], int(math.Round(rating))}
+	}
+	return standings
+}
+
+// sortStandings orders standings by highest points or rating first, falling
+// back to most wins and then name
+func sortStandings(standings []Standing, by string) {
+	key := func(s Standing) int { return s.Wins }
+	switch by {
+	case "points":
+		key = func(s Standing) int { return s.Points }
+	case "rating":
+		key = func(s Standing) int { return s.Rating }
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		if key(standings[i]) != key(standings[j]) {
+			return key(standings[i]) > key(standings[j])
+		}
+		if standings[i].Wins != standings[j].Wins {
+			return standings[i].Wins > standings[j].Wins
+		}
+		return standings[i].Name < standings[j].Name
+	})
+}
@@ -0,0 +1,113 @@
+package poker
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+)
+
+// Files a store is kept in when the command line doesn't name one
+const (
+	DefaultFileStore   = "game.db.json"
+	DefaultLogStore    = "game.db.log"
+	DefaultSQLiteStore = "game.db"
+)
+
+// StoreFlags are the command line flags choosing the PlayerStore, shared by
+// the CLI and the webserver
+type StoreFlags struct {
+	Kind   string
+	Source string
+	// Postgres is the deprecated -postgres flag, the same as -store postgres
+	// -db Postgres
+	Postgres string
+}
+
+// AddStoreFlags defines -store, -db and the deprecated -postgres on fs
+func AddStoreFlags(fs *flag.FlagSet) *StoreFlags {
+	s := &StoreFlags{}
+	fs.StringVar(&s.Kind, "store", "file", "player store to use: file, log, sqlite or postgres")
+	fs.StringVar(&s.Source, "db", "", "database file or postgres connection string, defaults to "+DefaultFileStore+", "+DefaultLogStore+" or "+DefaultSQLiteStore)
+	fs.StringVar(&s.Postgres, "postgres", "", "deprecated, use -store postgres -db {connection string}")
+	return s
+}
+
+// resolve turns the deprecated -postgres flag into -store and -db
+func (s *StoreFlags) resolve() (kind, source string, err error) {
+	if s.Postgres == "" {
+		return s.Kind, s.Source, nil
+	}
+	if s.Source != "" && s.Source != s.Postgres {
+		return "", "", errors.New("-postgres and -db both give a connection string, use -store postgres -db {connection string}")
+	}
+	log.Printf("-postgres is deprecated, use -store postgres -db {connection string}")
+	return "postgres", s.Postgres, nil
+}
+
+// Open opens the PlayerStore the flags choose, along with a func to close it
+func (s *StoreFlags) Open() (PlayerStore, func(), error) {
+	kind, source, err := s.resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+	return OpenPlayerStore(kind, source)
+}
+
+// OpenLeagues keeps the named leagues in dir, each in a store of the same
+// kind as the default league's. It returns nil for postgres, which doesn't
+// support them.
+func (s *StoreFlags) OpenLeagues(dir string) (*DirLeagueStore, error) {
+	kind, _, err := s.resolve()
+	if err != nil {
+		return nil, err
+	}
+	return OpenDirLeagueStore(kind, dir)
+}
+
+// OpenPlayerStore opens the kind of store kept at source: file, log or
+// sqlite default to their file in the working directory when source is
+// empty, postgres needs a connection string
+func OpenPlayerStore(kind, source string) (PlayerStore, func(), error) {
+	sourceOr := func(fallback string) string {
+		if source == "" {
+			return fallback
+		}
+		return source
+	}
+
+	switch kind {
+	case "file":
+		return FileSystemPlayerStoreFromFile(sourceOr(DefaultFileStore))
+	case "log":
+		return LogPlayerStoreFromFile(sourceOr(DefaultLogStore))
+	case "sqlite":
+		return SQLitePlayerStoreFromFile(sourceOr(DefaultSQLiteStore))
+	case "postgres":
+		if source == "" {
+			return nil, nil, errors.New("the postgres store needs a connection string, give one with -db")
+		}
+		return PostgresPlayerStoreFromDSN(source)
+	}
+	return nil, nil, fmt.Errorf("unknown store %q, want file, log, sqlite or postgres", kind)
+}
+
+// OpenDirLeagueStore keeps named leagues in dir, each in a file of the given
+// kind of store. There's nothing to open for postgres, so it returns nil.
+func OpenDirLeagueStore(kind, dir string) (*DirLeagueStore, error) {
+	switch kind {
+	case "file":
+		return NewDirLeagueStore(dir, ".db.json", func(path string) (PlayerStore, func(), error) {
+			return FileSystemPlayerStoreFromFile(path)
+		})
+	case "log":
+		return NewDirLeagueStore(dir, ".db.log", func(path string) (PlayerStore, func(), error) {
+			return LogPlayerStoreFromFile(path)
+		})
+	case "sqlite":
+		return NewDirLeagueStore(dir, ".db", func(path string) (PlayerStore, func(), error) {
+			return SQLitePlayerStoreFromFile(path)
+		})
+	}
+	return nil, nil
+}
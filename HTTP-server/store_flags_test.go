@@ -0,0 +1,54 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreFlags(t *testing.T) {
+	parse := func(t *testing.T, args ...string) *poker.StoreFlags {
+		t.Helper()
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags := poker.AddStoreFlags(fs)
+		assertNoError(t, fs.Parse(args))
+		return flags
+	}
+
+	t.Run("opens the kind of store asked for at -db", func(t *testing.T) {
+		for _, kind := range []string{"file", "log", "sqlite"} {
+			path := filepath.Join(t.TempDir(), "game")
+			store, closeFunc, err := parse(t, "-store", kind, "-db", path).Open()
+			assertNoError(t, err)
+			store.RecordWin("Cleo")
+			assertScoreEquals(t, store.GetPlayerScore("Cleo"), 1)
+			closeFunc()
+		}
+	})
+	t.Run("postgres without a connection string fails clearly", func(t *testing.T) {
+		_, _, err := parse(t, "-store", "postgres").Open()
+		if err == nil || !strings.Contains(err.Error(), "connection string") {
+			t.Errorf("got %v, want an error asking for a connection string", err)
+		}
+	})
+	t.Run("an unknown store fails", func(t *testing.T) {
+		if _, _, err := parse(t, "-store", "tape").Open(); err == nil {
+			t.Error("expected an error opening an unknown store")
+		}
+	})
+	t.Run("the deprecated -postgres can't disagree with -db", func(t *testing.T) {
+		_, _, err := parse(t, "-postgres", "postgres://one", "-db", "postgres://two").Open()
+		if err == nil {
+			t.Error("expected an error giving two connection strings")
+		}
+	})
+	t.Run("postgres has no league directory", func(t *testing.T) {
+		leagues, err := parse(t, "-postgres", "postgres://one").OpenLeagues(t.TempDir())
+		assertNoError(t, err)
+		if leagues != nil {
+			t.Errorf("got %v, want no league store for postgres", leagues)
+		}
+	})
+}
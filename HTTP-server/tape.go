@@ -1,17 +1,111 @@
 package poker
 
 import (
-	"io"
+	"fmt"
 	"os"
+	"path/filepath"
 )
 
-// Tape encapsulates the process of "when we write data we go from the beginning"
+// DefaultBackupGenerations is how many previous versions of the database
+// FileSystemPlayerStore keeps next to it, as name.1, name.2 and so on
+const DefaultBackupGenerations = 2
+
+// Tape encapsulates the process of "when we write data we go from the beginning".
+// Every write goes to a temp file that is synced and then renamed over File,
+// so a crash part way through leaves the previous contents in place. File is
+// then the renamed file, the one it was given is left open for its owner.
 type Tape struct {
-	File *os.File
+	File    *os.File
+	Backups int
+	// opened is true once File is a file the Tape opened, and so closes
+	opened bool
 }
 
 func (t *Tape) Write(p []byte) (n int, err error) {
-	t.File.Truncate(0)
-	t.File.Seek(0, io.SeekStart)
-	return t.File.Write(p)
+	path := t.File.Name()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, fmt.Errorf("problem creating temp file for %s, %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err = tmp.Write(p)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("problem writing temp file for %s, %v", path, err)
+	}
+
+	t.rotateBackups(path)
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("problem renaming temp file over %s, %v", path, err)
+	}
+	syncDir(filepath.Dir(path))
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0666)
+	if err != nil {
+		return 0, fmt.Errorf("problem reopening %s, %v", path, err)
+	}
+	t.swap(file)
+
+	return n, nil
+}
+
+// Close closes File if the Tape opened it, a File it was given is closed by
+// whoever gave it
+func (t *Tape) Close() error {
+	if !t.opened {
+		return nil
+	}
+	t.opened = false
+	return t.File.Close()
+}
+
+// swap makes file, which the Tape now owns, the current File
+func (t *Tape) swap(file *os.File) {
+	t.Close()
+	t.File = file
+	t.opened = true
+}
+
+// rotateBackups shifts name.1 to name.2 and so on, then links the current
+// file in as name.1 so it's still there if the following rename never happens
+func (t *Tape) rotateBackups(path string) {
+	if t.Backups <= 0 {
+		return
+	}
+	for i := t.Backups - 1; i > 0; i-- {
+		os.Rename(backupPath(path, i), backupPath(path, i+1))
+	}
+	os.Remove(backupPath(path, 1))
+	if err := os.Link(path, backupPath(path, 1)); err != nil {
+		copyFile(path, backupPath(path, 1))
+	}
+}
+
+func backupPath(path string, generation int) string {
+	return fmt.Sprintf("%s.%d", path, generation)
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0666)
+}
+
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
 }
@@ -3,6 +3,8 @@ package poker_test
 import (
 	poker "HTTP-server"
 	"io"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -14,8 +16,8 @@ func TestTape_Write(t *testing.T) {
 
 	tape.Write([]byte("abc"))
 
-	file.Seek(0, io.SeekStart)
-	newFileContents, _ := io.ReadAll(file)
+	tape.File.Seek(0, io.SeekStart)
+	newFileContents, _ := io.ReadAll(tape.File)
 
 	got := string(newFileContents)
 	want := "abc"
@@ -24,3 +26,61 @@ func TestTape_Write(t *testing.T) {
 		t.Errorf("got %q want %q", got, want)
 	}
 }
+
+func TestTape_WriteKeepsBackups(t *testing.T) {
+	file, clean := createTempFile(t, "1")
+	defer clean()
+
+	tape := &poker.Tape{File: file, Backups: 2}
+	tape.Write([]byte("2"))
+	tape.Write([]byte("3"))
+	tape.Write([]byte("4"))
+
+	assertFileContents(t, tape.File.Name(), "4")
+	assertFileContents(t, tape.File.Name()+".1", "3")
+	assertFileContents(t, tape.File.Name()+".2", "2")
+	if _, err := os.Stat(tape.File.Name() + ".3"); err == nil {
+		t.Errorf("expected only 2 backup generations to be kept")
+	}
+}
+
+func TestTape_WriteLeavesNoTempFiles(t *testing.T) {
+	file, clean := createTempFile(t, "12345")
+	defer clean()
+
+	tape := &poker.Tape{File: file}
+	tape.Write([]byte("abc"))
+
+	leftovers, _ := filepath.Glob(file.Name() + ".tmp-*")
+	if len(leftovers) != 0 {
+		t.Errorf("expected temp files to be cleaned up, found %v", leftovers)
+	}
+}
+
+func TestTape_WriteLeavesTheFileItWasGivenOpen(t *testing.T) {
+	file, clean := createTempFile(t, "12345")
+	defer clean()
+
+	tape := &poker.Tape{File: file}
+	tape.Write([]byte("abc"))
+	tape.Write([]byte("def"))
+
+	if _, err := file.Stat(); err != nil {
+		t.Errorf("expected the file given to the tape to still be open, %v", err)
+	}
+	assertNoError(t, tape.Close())
+	if _, err := tape.File.Stat(); err == nil {
+		t.Errorf("expected the file the tape opened to be closed")
+	}
+}
+
+func assertFileContents(t testing.TB, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read %s: %v", path, err)
+	}
+	if string(data) != want {
+		t.Errorf("got %q in %s, want %q", data, path, want)
+	}
+}
@@ -1,6 +1,10 @@
 package poker
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
@@ -9,6 +13,16 @@ type StubPlayerStore struct {
 	Scores   map[string]int
 	WinCalls []string
 	League   League
+	// Games holds every game passed to RecordGame
+	Games   []GameResult
+	Seasons []Season
+	// Scoring is the League's scoring, set through SetScoring
+	Scoring string
+	// Audit holds every rename and merge
+	Audit []AuditEntry
+	// Err is returned by every PlayerStoreV2 and GameStore method when set
+	Err error
+	mu  sync.Mutex
 }
 
 type ScheduledAlert struct {
@@ -17,13 +31,19 @@ type ScheduledAlert struct {
 }
 
 func (s *StubPlayerStore) DeletePlayer(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	// remove from Scores map
-	delete(s.Scores, name)
+	for scored := range s.Scores {
+		if samePlayer(scored, name) {
+			delete(s.Scores, scored)
+		}
+	}
 
 	// filter out from League slice
 	filtered := make(League, 0, len(s.League))
 	for _, p := range s.League {
-		if p.Name != name {
+		if !samePlayer(p.Name, name) {
 			filtered = append(filtered, p)
 		}
 	}
@@ -31,20 +51,245 @@ func (s *StubPlayerStore) DeletePlayer(name string) {
 }
 
 func (s *StubPlayerStore) GetPlayerScore(name string) int {
-	score := s.Scores[name]
+	score, _ := s.score(name)
 	return score
 }
 
+// score looks name up in Scores, matching on PlayerKey
+func (s *StubPlayerStore) score(name string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if score, ok := s.Scores[name]; ok {
+		return score, true
+	}
+	for scored, score := range s.Scores {
+		if samePlayer(scored, name) {
+			return score, true
+		}
+	}
+	return 0, false
+}
+
+// RecordWin remembers the call and counts the win in both Scores and League
 func (s *StubPlayerStore) RecordWin(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.WinCalls = append(s.WinCalls, name)
+
+	if player := s.League.FindPlayer(name); player != nil {
+		player.Wins++
+		name = player.Name
+	} else {
+		s.League = append(s.League, Player{name, 1})
+	}
+
+	if s.Scores == nil {
+		s.Scores = map[string]int{}
+	}
+	s.Scores[name]++
 }
 
 func (s *StubPlayerStore) GetLeague() League {
-	return s.League
+	s.mu.Lock()
+	league := s.League.clone()
+	s.mu.Unlock()
+	return sortByWins(league)
+}
+
+func (s *StubPlayerStore) GetPlayerScoreContext(ctx context.Context, name string) (int, error) {
+	if err := s.stubErr(ctx); err != nil {
+		return 0, err
+	}
+	score, ok := s.score(name)
+	if !ok {
+		return 0, ErrPlayerNotFound
+	}
+	return score, nil
+}
+
+func (s *StubPlayerStore) RecordWinContext(ctx context.Context, name string) error {
+	if err := s.stubErr(ctx); err != nil {
+		return err
+	}
+	s.RecordWin(name)
+	return nil
+}
+
+func (s *StubPlayerStore) GetLeagueContext(ctx context.Context) (League, error) {
+	if err := s.stubErr(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetLeague(), nil
+}
+
+func (s *StubPlayerStore) DeletePlayerContext(ctx context.Context, name string) error {
+	if err := s.stubErr(ctx); err != nil {
+		return err
+	}
+	if _, ok := s.score(name); !ok && s.GetLeague().FindPlayer(name) == nil {
+		return ErrPlayerNotFound
+	}
+	s.DeletePlayer(name)
+	return nil
+}
+
+// RecordGame keeps game in Games and records its winner through RecordWin
+func (s *StubPlayerStore) RecordGame(ctx context.Context, game GameResult) (GameResult, error) {
+	if err := s.stubErr(ctx); err != nil {
+		return GameResult{}, err
+	}
+	game, err := game.normalise()
+	if err != nil {
+		return game, err
+	}
+	s.mu.Lock()
+	game.ID = len(s.Games) + 1
+	if game.Time.IsZero() {
+		game.Time = time.Now().UTC()
+	}
+	if season, ok := (playerDB{Seasons: s.Seasons}).activeSeason(game.Time); ok && game.Season == 0 {
+		game.Season = season.ID
+	}
+	s.Games = append(s.Games, game)
+	s.mu.Unlock()
+
+	s.RecordWin(game.Winner)
+	return game, nil
+}
+
+func (s *StubPlayerStore) GetGames(ctx context.Context, filter GameFilter) ([]GameResult, error) {
+	if err := s.stubErr(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return FilterGames(s.Games, filter), nil
+}
+
+func (s *StubPlayerStore) StartSeason(ctx context.Context, season Season) (Season, error) {
+	if err := s.stubErr(ctx); err != nil {
+		return Season{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db := playerDB{Seasons: s.Seasons}
+	season, err := db.startSeason(season)
+	if err != nil {
+		return Season{}, err
+	}
+	s.Seasons = db.Seasons
+	return season, nil
+}
+
+func (s *StubPlayerStore) GetSeasons(ctx context.Context) ([]Season, error) {
+	if err := s.stubErr(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Seasons, nil
+}
+
+func (s *StubPlayerStore) GetSeasonLeague(ctx context.Context, id int) (League, error) {
+	if err := s.stubErr(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return playerDB{Games: s.Games, Seasons: s.Seasons, Scoring: s.Scoring}.seasonStandings(id)
+}
+
+func (s *StubPlayerStore) GetScoring(ctx context.Context) (string, error) {
+	if err := s.stubErr(ctx); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return playerDB{Seasons: s.Seasons, Scoring: s.Scoring}.currentScoring(time.Now()), nil
+}
+
+func (s *StubPlayerStore) SetScoring(ctx context.Context, name string) error {
+	if err := s.stubErr(ctx); err != nil {
+		return err
+	}
+	if _, err := ScoringByName(name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Scoring = name
+	return nil
+}
+
+// GetPoints scores the recorded Games, wins counted straight into League
+// by RecordWin aren't scored
+func (s *StubPlayerStore) GetPoints(ctx context.Context) (map[string]int, error) {
+	if err := s.stubErr(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return playerDB{Games: s.Games, Seasons: s.Seasons, Scoring: s.Scoring}.currentPoints(time.Now())
+}
+
+func (s *StubPlayerStore) RenamePlayer(ctx context.Context, from, to string) error {
+	return s.editPlayers(ctx, func(db *playerDB) (AuditEntry, error) {
+		return db.renamePlayer(ctx, from, to)
+	})
+}
+
+func (s *StubPlayerStore) MergePlayers(ctx context.Context, into string, from []string) error {
+	return s.editPlayers(ctx, func(db *playerDB) (AuditEntry, error) {
+		return db.mergePlayers(ctx, into, from)
+	})
+}
+
+func (s *StubPlayerStore) GetAuditLog(ctx context.Context) ([]AuditEntry, error) {
+	if err := s.stubErr(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Audit, nil
+}
+
+// editPlayers applies edit to the League and Games, then moves the Scores of
+// the players it changed onto the player they became. Players only known
+// from Scores can't be edited.
+func (s *StubPlayerStore) editPlayers(ctx context.Context, edit func(db *playerDB) (AuditEntry, error)) error {
+	if err := s.stubErr(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db := playerDB{League: s.League, Games: s.Games, Audit: s.Audit}.clone()
+	entry, err := edit(&db)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range entry.From {
+		if score, ok := s.Scores[name]; ok {
+			delete(s.Scores, name)
+			s.Scores[entry.To] += score
+		}
+	}
+	s.League, s.Games, s.Audit = db.League, db.Games, db.Audit
+	return nil
+}
+
+func (s *StubPlayerStore) stubErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Err
 }
 
 func AssertPlayerWin(t testing.TB, store *StubPlayerStore, winner string) {
 	t.Helper()
+	store.mu.Lock()
+	defer store.mu.Unlock()
 
 	if len(store.WinCalls) != 1 {
 		t.Fatalf("got %d calls to RecordWin want %d", len(store.WinCalls), 1)
@@ -54,3 +299,142 @@ func AssertPlayerWin(t testing.TB, store *StubPlayerStore, winner string) {
 		t.Errorf("did not store correct winner got %q want %q", store.WinCalls[0], winner)
 	}
 }
+
+// RunPlayerStoreSuite checks that the PlayerStore made by newStore honours
+// the whole PlayerStore contract, newStore must return an empty store and
+// should register any cleanup with t.Cleanup
+func RunPlayerStoreSuite(t *testing.T, newStore func(t *testing.T) PlayerStore) {
+	t.Helper()
+
+	t.Run("an empty store has an empty League", func(t *testing.T) {
+		store := newStore(t)
+		if league := store.GetLeague(); len(league) != 0 {
+			t.Errorf("got League %v, want it empty", league)
+		}
+	})
+	t.Run("counts every win", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Pepper")
+		store.RecordWin("Pepper")
+		store.RecordWin("Floyd")
+
+		assertSuiteScore(t, store, "Pepper", 2)
+		assertSuiteScore(t, store, "Floyd", 1)
+	})
+	t.Run("League is sorted by most wins first", func(t *testing.T) {
+		store := newStore(t)
+		for name, wins := range map[string]int{"Cleo": 1, "Chris": 3, "Floyd": 2} {
+			for i := 0; i < wins; i++ {
+				store.RecordWin(name)
+			}
+		}
+
+		assertSuiteLeague(t, store.GetLeague(), League{{"Chris", 3}, {"Floyd", 2}, {"Cleo", 1}})
+	})
+	t.Run("League is a snapshot", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Pepper")
+		league := store.GetLeague()
+		league[0].Wins = 100
+
+		assertSuiteScore(t, store, "Pepper", 1)
+	})
+	t.Run("deletes a player and their wins", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Pepper")
+		store.RecordWin("Floyd")
+		store.DeletePlayer("Pepper")
+
+		assertSuiteScore(t, store, "Pepper", 0)
+		assertSuiteLeague(t, store.GetLeague(), League{{"Floyd", 1}})
+
+		store.RecordWin("Pepper")
+		assertSuiteScore(t, store, "Pepper", 1)
+	})
+	t.Run("unknown players have no wins and deleting them changes nothing", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Floyd")
+		store.DeletePlayer("Apollo")
+
+		assertSuiteScore(t, store, "Apollo", 0)
+		assertSuiteLeague(t, store.GetLeague(), League{{"Floyd", 1}})
+
+		if v2, ok := store.(PlayerStoreV2); ok {
+			if _, err := v2.GetPlayerScoreContext(context.Background(), "Apollo"); !errors.Is(err, ErrPlayerNotFound) {
+				t.Errorf("got error %v getting an unknown score, want %v", err, ErrPlayerNotFound)
+			}
+			if err := v2.DeletePlayerContext(context.Background(), "Apollo"); !errors.Is(err, ErrPlayerNotFound) {
+				t.Errorf("got error %v deleting an unknown player, want %v", err, ErrPlayerNotFound)
+			}
+		}
+	})
+	t.Run("names are unicode safe", func(t *testing.T) {
+		store := newStore(t)
+		names := []string{"Zoë", "李雷", "🂡 Ace", "O'Brien \"Junior\""}
+		for i, name := range names {
+			for j := 0; j <= i; j++ {
+				store.RecordWin(name)
+			}
+		}
+
+		for i, name := range names {
+			assertSuiteScore(t, store, name, i+1)
+		}
+		if league := store.GetLeague(); len(league) != len(names) {
+			t.Errorf("got League %v, want %d players", league, len(names))
+		}
+	})
+	t.Run("names differing only by case, spacing or normalisation are one player", func(t *testing.T) {
+		store := newStore(t)
+		// the last is Zoë with a combining diaeresis
+		for _, name := range []string{"Zoë", "zoë", " ZOË ", "Zoe\u0308"} {
+			store.RecordWin(name)
+		}
+		store.RecordWin("Floyd")
+
+		assertSuiteScore(t, store, "zoË", 4)
+		assertSuiteLeague(t, store.GetLeague(), League{{"Zoë", 4}, {"Floyd", 1}})
+
+		store.DeletePlayer("ZOË")
+		assertSuiteScore(t, store, "Zoë", 0)
+		assertSuiteLeague(t, store.GetLeague(), League{{"Floyd", 1}})
+	})
+	t.Run("concurrent wins are all counted", func(t *testing.T) {
+		const workers = 20
+		const winsPerWorker = 10
+		store := newStore(t)
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < winsPerWorker; j++ {
+					store.RecordWin("Pepper")
+					store.RecordWin(fmt.Sprintf("player-%d", i))
+					store.GetLeague()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		assertSuiteScore(t, store, "Pepper", workers*winsPerWorker)
+		for i := 0; i < workers; i++ {
+			assertSuiteScore(t, store, fmt.Sprintf("player-%d", i), winsPerWorker)
+		}
+	})
+}
+
+func assertSuiteScore(t testing.TB, store PlayerStore, name string, want int) {
+	t.Helper()
+	if got := store.GetPlayerScore(name); got != want {
+		t.Errorf("got %d wins for %q, want %d", got, name, want)
+	}
+}
+
+func assertSuiteLeague(t testing.TB, got, want League) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got League %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+package poker
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Tombstone keeps everything removed when a player was deleted
+type Tombstone struct {
+	ID     int       `json:"id"`
+	Player string    `json:"player"`
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor,omitempty"`
+	// Wins are the player's carried over wins and Games the games they won
+	Wins  int          `json:"wins,omitempty"`
+	Games []GameResult `json:"games,omitempty"`
+}
+
+// TombstoneStore is implemented by stores whose DeletePlayer is a soft
+// delete, keeping a tombstone the player can be restored from
+type TombstoneStore interface {
+	// GetDeleted returns every tombstone, oldest first
+	GetDeleted(ctx context.Context) ([]Tombstone, error)
+	// RestorePlayer brings back the player's most recent tombstone, adding
+	// it to anything they've won since
+	RestorePlayer(ctx context.Context, name string) error
+	// PurgePlayer permanently removes the player and all their tombstones,
+	// along with the games they won and their place in every other game
+	PurgePlayer(ctx context.Context, name string) error
+}
+
+// softDeletePlayer moves name's wins and games into a tombstone
+func (d *playerDB) softDeletePlayer(ctx context.Context, name string) error {
+	tombstone, found := d.removePlayer(name)
+	if !found {
+		return ErrPlayerNotFound
+	}
+
+	tombstone.ID = 1
+	for _, existing := range d.Deleted {
+		if existing.ID >= tombstone.ID {
+			tombstone.ID = existing.ID + 1
+		}
+	}
+	tombstone.Time = time.Now().UTC()
+	tombstone.Actor = ActorFromContext(ctx)
+	d.Deleted = append(d.Deleted, tombstone)
+	d.audit(ctx, AuditDelete, []string{tombstone.Player}, "")
+	return nil
+}
+
+// restorePlayer puts back the contents of name's most recent tombstone
+func (d *playerDB) restorePlayer(ctx context.Context, name string) error {
+	latest := -1
+	for i, tombstone := range d.Deleted {
+		if samePlayer(tombstone.Player, name) {
+			latest = i
+		}
+	}
+	if latest == -1 {
+		return ErrPlayerNotFound
+	}
+	tombstone := d.Deleted[latest]
+	d.Deleted = append(d.Deleted[:latest:latest], d.Deleted[latest+1:]...)
+
+	if tombstone.Wins > 0 {
+		d.carryWins(tombstone.Player, tombstone.Wins)
+	}
+	d.Games = append(d.Games, tombstone.Games...)
+	sort.SliceStable(d.Games, func(i, j int) bool { return d.Games[i].ID < d.Games[j].ID })
+
+	// a player who has won again since keeps the name they won under
+	d.canonicalise()
+	d.audit(ctx, AuditRestore, []string{tombstone.Player}, "")
+	return nil
+}
+
+// purgePlayer removes name and their tombstones for good, taking them out
+// of the finishing order of every game kept, undone or in a tombstone, so
+// they're left in no points or ratings and can't be brought back
+func (d *playerDB) purgePlayer(ctx context.Context, name string) error {
+	removed, found := d.removePlayer(name)
+	display := removed.Player
+	forgotten := func(player string) {
+		if display == "" {
+			display = player
+		}
+		found = true
+	}
+
+	d.Games = forgetPlayer(d.Games, name, forgotten)
+	d.retally()
+
+	deleted := []Tombstone{}
+	for _, tombstone := range d.Deleted {
+		if samePlayer(tombstone.Player, name) {
+			display, found = tombstone.Player, true
+			continue
+		}
+		tombstone.Games = forgetPlayer(tombstone.Games, name, forgotten)
+		deleted = append(deleted, tombstone)
+	}
+
+	undone := []Undo{}
+	for _, undo := range d.Undone {
+		if undo.Games = forgetPlayer(undo.Games, name, forgotten); len(undo.Games) > 0 {
+			undone = append(undone, undo)
+		}
+	}
+	if !found {
+		return ErrPlayerNotFound
+	}
+	d.Deleted, d.Undone = deleted, undone
+	d.audit(ctx, AuditPurge, []string{display}, "")
+	return nil
+}
+
+// forgetPlayer returns games without those name won and without name in
+// the finishing order of the rest, calling forgotten with the name as it
+// was written each time it's dropped. games itself is left unchanged.
+func forgetPlayer(games []GameResult, name string, forgotten func(player string)) []GameResult {
+	kept := make([]GameResult, 0, len(games))
+	for _, game := range games {
+		if samePlayer(game.Winner, name) {
+			forgotten(game.Winner)
+			continue
+		}
+		finishing := make([]string, 0, len(game.Finishing))
+		for _, player := range game.Finishing {
+			if samePlayer(player, name) {
+				forgotten(player)
+				continue
+			}
+			finishing = append(finishing, player)
+		}
+		if len(finishing) < len(game.Finishing) {
+			game.Finishing = finishing
+		}
+		kept = append(kept, game)
+	}
+	return kept
+}
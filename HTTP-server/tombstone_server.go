@@ -0,0 +1,112 @@
+package poker
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AdminActor is the audit Actor of requests bearing the admin token
+const AdminActor = "admin"
+
+// actorHandler names who is making each request, for the audit log. Only
+// the admin token is verified, anyone else is known by their address with
+// the name they give in the X-User header marked as unverified.
+func (p *PlayerServer) actorHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor, _, _ := net.SplitHostPort(r.RemoteAddr)
+		if claimed := strings.TrimSpace(r.Header.Get("X-User")); claimed != "" {
+			actor = fmt.Sprintf("%q (unverified) from %s", claimed, actor)
+		}
+		if p.isAdmin(r) {
+			actor = AdminActor
+		}
+		next.ServeHTTP(w, r.WithContext(WithActor(r.Context(), actor)))
+	})
+}
+
+// isAdmin reports whether r bears the admin token
+func (p *PlayerServer) isAdmin(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && p.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(p.adminToken)) == 1
+}
+
+// deletedHandler lists the tombstones of deleted players
+func (p *PlayerServer) deletedHandler(w http.ResponseWriter, r *http.Request) {
+	if p.tombstones == nil {
+		http.Error(w, "this store does not keep deleted players", http.StatusNotImplemented)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	deleted, err := p.tombstones.GetDeleted(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deleted)
+}
+
+// restoreHandler brings back a deleted player on POST /restore/{name}. Like
+// purging, it's admin only, so the tombstones are the admin's to manage.
+func (p *PlayerServer) restoreHandler(w http.ResponseWriter, r *http.Request) {
+	if p.tombstones == nil {
+		http.Error(w, "this store does not keep deleted players", http.StatusNotImplemented)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !p.isAdmin(r) {
+		http.Error(w, "restoring a player is admin only", http.StatusForbidden)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/restore/")
+	if err := p.tombstones.RestorePlayer(r.Context(), name); err != nil {
+		storeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *PlayerServer) purgePlayer(w http.ResponseWriter, r *http.Request, name string) {
+	if p.tombstones == nil {
+		http.Error(w, "this store does not keep deleted players", http.StatusNotImplemented)
+		return
+	}
+	if !p.isAdmin(r) {
+		http.Error(w, "purging a player is admin only", http.StatusForbidden)
+		return
+	}
+	if err := p.tombstones.PurgePlayer(r.Context(), name); err != nil {
+		storeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// auditHandler returns the audit log, filtered by the optional player and
+// action query parameters
+func (p *PlayerServer) auditHandler(w http.ResponseWriter, r *http.Request) {
+	if p.audit == nil {
+		http.Error(w, "this store does not keep an audit log", http.StatusNotImplemented)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	audit, err := p.audit.GetAuditLog(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	query := r.URL.Query()
+	filter := AuditFilter{Player: query.Get("player"), Action: AuditAction(query.Get("action"))}
+	writeJSON(w, http.StatusOK, FilterAudit(audit, filter))
+}
@@ -0,0 +1,238 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFileSystemStoreSoftDelete(t *testing.T) {
+	ctx := poker.WithActor(context.Background(), "Cleo")
+
+	newStore := func(t *testing.T) *poker.FileSystemPlayerStore {
+		return mustMakeFileSystemStore(t, `[{"Name": "Pepper", "Wins": 3}, {"Name": "Floyd", "Wins": 1}]`)
+	}
+
+	t.Run("deleting keeps a tombstone of who deleted what", func(t *testing.T) {
+		store := newStore(t)
+		mustRecordGame(t, store, poker.GameResult{Winner: "Pepper"})
+
+		assertNoError(t, store.DeletePlayerContext(ctx, "pepper"))
+		assertLeague(t, store.GetLeague(), poker.League{{"Floyd", 1}})
+
+		deleted, err := store.GetDeleted(ctx)
+		assertNoError(t, err)
+		if len(deleted) != 1 {
+			t.Fatalf("got %d tombstones, want 1", len(deleted))
+		}
+		got := deleted[0]
+		if got.Player != "Pepper" || got.Actor != "Cleo" || got.Wins != 3 || len(got.Games) != 1 || got.Time.IsZero() {
+			t.Errorf("got tombstone %+v", got)
+		}
+	})
+	t.Run("restoring adds the player's wins to any won since", func(t *testing.T) {
+		store := newStore(t)
+		mustRecordGame(t, store, poker.GameResult{Winner: "Pepper"})
+		assertNoError(t, store.DeletePlayerContext(ctx, "Pepper"))
+		store.RecordWin("Pepper")
+
+		assertNoError(t, store.RestorePlayer(ctx, "Pepper"))
+
+		assertScoreEquals(t, store.GetPlayerScore("Pepper"), 5)
+		games, err := store.GetGames(ctx, poker.GameFilter{Player: "Pepper"})
+		assertNoError(t, err)
+		if len(games) != 2 || games[0].ID > games[1].ID {
+			t.Errorf("got games %+v, want both of Pepper's games in order", games)
+		}
+		deleted, err := store.GetDeleted(ctx)
+		assertNoError(t, err)
+		if len(deleted) != 0 {
+			t.Errorf("got tombstones %+v, want none", deleted)
+		}
+	})
+	t.Run("purging removes the player and their tombstones", func(t *testing.T) {
+		store := newStore(t)
+		assertNoError(t, store.DeletePlayerContext(ctx, "Pepper"))
+		store.RecordWin("Pepper")
+
+		assertNoError(t, store.PurgePlayer(ctx, "Pepper"))
+
+		assertLeague(t, store.GetLeague(), poker.League{{"Floyd", 1}})
+		assertError(t, store.RestorePlayer(ctx, "Pepper"), poker.ErrPlayerNotFound)
+		assertError(t, store.PurgePlayer(ctx, "Pepper"), poker.ErrPlayerNotFound)
+	})
+	t.Run("purging takes the player out of every game they played", func(t *testing.T) {
+		store := newStore(t)
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Floyd", "pepper", "Cleo"}})
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Cleo", "Pepper"}})
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Floyd", "Cleo", "Pepper"}})
+		_, err := store.UndoGames(ctx, 1)
+		assertNoError(t, err)
+
+		assertNoError(t, store.PurgePlayer(ctx, "Pepper"))
+
+		isPepper := func(name string) bool { return strings.EqualFold(name, "Pepper") }
+		games, err := store.GetGames(ctx, poker.GameFilter{})
+		assertNoError(t, err)
+		undone, err := store.GetUndone(ctx)
+		assertNoError(t, err)
+		for _, game := range games {
+			if slices.ContainsFunc(game.Finishing, isPepper) {
+				t.Errorf("got game %+v, want Pepper taken out", game)
+			}
+		}
+		if len(undone) != 1 || slices.ContainsFunc(undone[0].Games[0].Finishing, isPepper) {
+			t.Errorf("got undone %+v, want Pepper taken out", undone)
+		}
+		for name := range poker.ScorePoints(games, poker.ScoringFunc(poker.PlayersBeatenScoring)) {
+			if isPepper(name) {
+				t.Errorf("expected %s to score no points", name)
+			}
+		}
+		for name := range poker.EloRatings(games) {
+			if isPepper(name) {
+				t.Errorf("expected %s to have no rating", name)
+			}
+		}
+		_, err = store.RedoGames(ctx, undone[0].ID)
+		assertNoError(t, err)
+		assertLeague(t, store.GetLeague(), poker.League{{"Floyd", 3}, {"Cleo", 1}})
+	})
+	t.Run("deletes, restores and purges are audited", func(t *testing.T) {
+		store := newStore(t)
+		assertNoError(t, store.DeletePlayerContext(ctx, "Pepper"))
+		assertNoError(t, store.RestorePlayer(ctx, "Pepper"))
+		assertNoError(t, store.PurgePlayer(ctx, "Pepper"))
+
+		audit, err := store.GetAuditLog(ctx)
+		assertNoError(t, err)
+		if len(audit) != 3 {
+			t.Fatalf("got %d audit entries, want 3", len(audit))
+		}
+		for i, action := range []poker.AuditAction{poker.AuditDelete, poker.AuditRestore, poker.AuditPurge} {
+			assertAuditEntry(t, audit[i], action, []string{"Pepper"}, "")
+			if audit[i].Actor != "Cleo" {
+				t.Errorf("got actor %q, want Cleo", audit[i].Actor)
+			}
+		}
+	})
+}
+
+func TestSoftDeleteEndpoints(t *testing.T) {
+	newServer := func(t *testing.T) (*poker.PlayerServer, *poker.FileSystemPlayerStore) {
+		t.Helper()
+		return mustMakeFileSystemServer(t, `[{"Name": "Pepper", "Wins": 3}]`)
+	}
+
+	t.Run("DELETE then POST /restore/{name} brings a player back", func(t *testing.T) {
+		server, _ := newServer(t)
+
+		assertStatus(t, serve(server, newDeletePlayerRequest("Pepper")).Code, http.StatusOK)
+		assertStatus(t, serve(server, newGetScoreRequest("Pepper")).Code, http.StatusNotFound)
+
+		response := serve(server, newRequest(http.MethodGet, "/deleted"))
+		assertStatus(t, response.Code, http.StatusOK)
+		var deleted []poker.Tombstone
+		assertNoError(t, json.NewDecoder(response.Body).Decode(&deleted))
+		if len(deleted) != 1 || deleted[0].Player != "Pepper" {
+			t.Errorf("got tombstones %+v", deleted)
+		}
+
+		assertStatus(t, serve(server, asAdmin(newRequest(http.MethodPost, "/restore/Pepper"))).Code, http.StatusOK)
+		response = serve(server, newGetScoreRequest("Pepper"))
+		assertResponseBody(t, response.Body.String(), "3")
+
+		assertStatus(t, serve(server, asAdmin(newRequest(http.MethodPost, "/restore/Apollo"))).Code, http.StatusNotFound)
+	})
+	t.Run("restoring is admin only", func(t *testing.T) {
+		server, store := newServer(t)
+		serve(server, newDeletePlayerRequest("Pepper"))
+
+		request := newRequest(http.MethodPost, "/restore/Pepper")
+		assertStatus(t, serve(server, request).Code, http.StatusForbidden)
+		request.Header.Set("Authorization", "Bearer wrong")
+		assertStatus(t, serve(server, request).Code, http.StatusForbidden)
+
+		deleted, err := store.GetDeleted(context.Background())
+		assertNoError(t, err)
+		if len(deleted) != 1 {
+			t.Errorf("got tombstones %+v, want Pepper's still there", deleted)
+		}
+	})
+	t.Run("purging is admin only", func(t *testing.T) {
+		server, store := newServer(t)
+
+		request := newRequest(http.MethodDelete, "/players/Pepper?purge=true")
+		assertStatus(t, serve(server, request).Code, http.StatusForbidden)
+		request.Header.Set("Authorization", "Bearer wrong")
+		assertStatus(t, serve(server, request).Code, http.StatusForbidden)
+		assertScoreEquals(t, store.GetPlayerScore("Pepper"), 3)
+
+		assertStatus(t, serve(server, asAdmin(request)).Code, http.StatusOK)
+		assertStatus(t, serve(server, asAdmin(newRequest(http.MethodPost, "/restore/Pepper"))).Code, http.StatusNotFound)
+	})
+	t.Run("a purged player is gone from /games and /league", func(t *testing.T) {
+		server, store := newServer(t)
+		mustRecordGame(t, store, poker.GameResult{Finishing: []string{"Cleo", "Pepper", "Floyd"}})
+
+		assertStatus(t, serve(server, asAdmin(newRequest(http.MethodDelete, "/players/Pepper?purge=true"))).Code, http.StatusOK)
+
+		for _, path := range []string{"/games", "/league?sort=points", "/league?sort=rating"} {
+			response := serve(server, newRequest(http.MethodGet, path))
+			assertStatus(t, response.Code, http.StatusOK)
+			if strings.Contains(response.Body.String(), "Pepper") {
+				t.Errorf("got %s from %s, want Pepper gone", response.Body.String(), path)
+			}
+		}
+	})
+	t.Run("purging is forbidden without an admin token", func(t *testing.T) {
+		server := mustMakePlayerServer(t, mustMakeFileSystemStore(t, `[{"Name": "Pepper", "Wins": 3}]`))
+
+		request := newRequest(http.MethodDelete, "/players/Pepper?purge=true")
+		request.Header.Set("Authorization", "Bearer ")
+		assertStatus(t, serve(server, request).Code, http.StatusForbidden)
+	})
+	t.Run("GET /audit shows who changed what", func(t *testing.T) {
+		server, _ := newServer(t)
+		request := newDeletePlayerRequest("Pepper")
+		request.Header.Set("X-User", "Cleo")
+		request.RemoteAddr = "192.0.2.1:1234"
+		serve(server, request)
+		request = asAdmin(newRequest(http.MethodPost, "/restore/Pepper"))
+		request.Header.Set("X-User", "Cleo")
+		serve(server, request)
+
+		response := serve(server, newRequest(http.MethodGet, "/audit?player=pepper&action=delete"))
+		assertStatus(t, response.Code, http.StatusOK)
+		var audit []poker.AuditEntry
+		assertNoError(t, json.NewDecoder(response.Body).Decode(&audit))
+		if len(audit) != 1 {
+			t.Fatalf("got audit %+v, want the one delete", audit)
+		}
+		assertAuditEntry(t, audit[0], poker.AuditDelete, []string{"Pepper"}, "")
+		if want := `"Cleo" (unverified) from 192.0.2.1`; audit[0].Actor != want {
+			t.Errorf("got actor %q, want %q", audit[0].Actor, want)
+		}
+
+		response = serve(server, newRequest(http.MethodGet, "/audit?action=restore"))
+		audit = nil
+		assertNoError(t, json.NewDecoder(response.Body).Decode(&audit))
+		if len(audit) != 1 || audit[0].Actor != poker.AdminActor {
+			t.Errorf("got audit %+v, want the restore made by the admin", audit)
+		}
+	})
+	t.Run("returns 501 when the store has no tombstones or audit log", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &legacyPlayerStore{scores: map[string]int{}})
+		for _, request := range []*http.Request{
+			newRequest(http.MethodGet, "/deleted"),
+			newRequest(http.MethodPost, "/restore/Pepper"),
+			newRequest(http.MethodGet, "/audit"),
+		} {
+			assertStatus(t, serve(server, request).Code, http.StatusNotImplemented)
+		}
+	})
+}
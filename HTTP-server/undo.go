@@ -0,0 +1,107 @@
+package poker
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNothingToUndo is returned when undoing with no recorded games left
+	ErrNothingToUndo = errors.New("no recorded games to undo")
+	// ErrInvalidUndo is returned when asked to undo fewer than one game
+	ErrInvalidUndo = errors.New("can only undo one or more games")
+	// ErrUndoNotFound is returned when redoing an undo that doesn't exist
+	ErrUndoNotFound = errors.New("undo not found")
+)
+
+// Undo keeps the games taken back by an undo so they can be put back
+type Undo struct {
+	ID    int          `json:"id"`
+	Time  time.Time    `json:"time"`
+	Actor string       `json:"actor,omitempty"`
+	Games []GameResult `json:"games"`
+}
+
+// UndoStore is implemented by stores that can take back recorded games
+type UndoStore interface {
+	// UndoGames removes the last n games recorded, or as many as there are,
+	// and returns them most recent first
+	UndoGames(ctx context.Context, n int) ([]GameResult, error)
+	// GetUndone returns every undo that hasn't been redone, oldest first
+	GetUndone(ctx context.Context) ([]Undo, error)
+	// RedoGames puts back the games of undo id and returns them
+	RedoGames(ctx context.Context, id int) ([]GameResult, error)
+}
+
+// undoGames moves the n games with the highest IDs, the last recorded
+// whatever time they were played at, into an Undo. Carried over wins were
+// never recorded as games so can't be undone.
+func (d *playerDB) undoGames(ctx context.Context, n int) ([]GameResult, error) {
+	if n < 1 {
+		return nil, ErrInvalidUndo
+	}
+	if len(d.Games) == 0 {
+		return nil, ErrNothingToUndo
+	}
+
+	games := make([]GameResult, len(d.Games))
+	copy(games, d.Games)
+	sort.SliceStable(games, func(i, j int) bool { return games[i].ID > games[j].ID })
+	undone := games[:min(n, len(games))]
+
+	undoing := map[int]bool{}
+	winners := make([]string, len(undone))
+	for i, game := range undone {
+		undoing[game.ID] = true
+		winners[i] = game.Winner
+	}
+	kept := d.Games[:0]
+	for _, game := range d.Games {
+		if !undoing[game.ID] {
+			kept = append(kept, game)
+		}
+	}
+	d.Games = kept
+	d.retally()
+
+	undo := Undo{ID: 1, Time: time.Now().UTC(), Actor: ActorFromContext(ctx), Games: undone}
+	for _, existing := range d.Undone {
+		if existing.ID >= undo.ID {
+			undo.ID = existing.ID + 1
+		}
+	}
+	d.Undone = append(d.Undone, undo)
+	d.audit(ctx, AuditUndo, winners, strconv.Itoa(undo.ID))
+	return undone, nil
+}
+
+// redoGames puts the games of undo id back, under the IDs and seasons they
+// were recorded with
+func (d *playerDB) redoGames(ctx context.Context, id int) ([]GameResult, error) {
+	i := -1
+	for j, undo := range d.Undone {
+		if undo.ID == id {
+			i = j
+		}
+	}
+	if i == -1 {
+		return nil, ErrUndoNotFound
+	}
+	undo := d.Undone[i]
+	d.Undone = append(d.Undone[:i:i], d.Undone[i+1:]...)
+
+	winners := make([]string, len(undo.Games))
+	for j, game := range undo.Games {
+		winners[j] = game.Winner
+	}
+	d.Games = append(d.Games, undo.Games...)
+	sort.SliceStable(d.Games, func(i, j int) bool { return d.Games[i].ID < d.Games[j].ID })
+
+	// the players may have been renamed or merged since
+	d.canonicalise()
+	d.audit(ctx, AuditRedo, winners, strconv.Itoa(undo.ID))
+	return undo.Games, nil
+}
@@ -0,0 +1,204 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestFileSystemStoreUndo(t *testing.T) {
+	ctx := poker.WithActor(context.Background(), "Cleo")
+
+	newStore := func(t *testing.T) *poker.FileSystemPlayerStore {
+		return mustMakeFileSystemStore(t, `[{"Name": "Pepper", "Wins": 3}]`)
+	}
+
+	t.Run("undoes the most recently recorded games", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Chris")
+		store.RecordWin("Floyd")
+		store.RecordWin("Floyd")
+
+		undone, err := store.UndoGames(ctx, 2)
+		assertNoError(t, err)
+
+		if len(undone) != 2 || undone[0].ID != 3 || undone[1].ID != 2 {
+			t.Errorf("got undone games %+v, want games 3 and 2", undone)
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Pepper", 3}, {"Chris", 1}})
+	})
+	t.Run("undoes every game when asked for more than there are", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Chris")
+
+		undone, err := store.UndoGames(ctx, 5)
+		assertNoError(t, err)
+
+		if len(undone) != 1 {
+			t.Errorf("got %d undone games, want 1", len(undone))
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Pepper", 3}})
+	})
+	t.Run("carried over wins can't be undone", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.UndoGames(ctx, 1)
+		assertError(t, err, poker.ErrNothingToUndo)
+		_, err = store.UndoGames(ctx, 0)
+		assertError(t, err, poker.ErrInvalidUndo)
+		assertScoreEquals(t, store.GetPlayerScore("Pepper"), 3)
+	})
+	t.Run("undone games are kept until they're redone", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Chris")
+		store.RecordWin("Floyd")
+
+		_, err := store.UndoGames(ctx, 2)
+		assertNoError(t, err)
+		store.RecordWin("Cleo")
+
+		undone, err := store.GetUndone(ctx)
+		assertNoError(t, err)
+		if len(undone) != 1 || len(undone[0].Games) != 2 || undone[0].Actor != "Cleo" {
+			t.Fatalf("got undone %+v, want one undo of two games", undone)
+		}
+
+		redone, err := store.RedoGames(ctx, undone[0].ID)
+		assertNoError(t, err)
+		if len(redone) != 2 {
+			t.Errorf("got %d redone games, want 2", len(redone))
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Pepper", 3}, {"Chris", 1}, {"Cleo", 1}, {"Floyd", 1}})
+
+		games, err := store.GetGames(ctx, poker.GameFilter{})
+		assertNoError(t, err)
+		for i, game := range games {
+			if game.ID != i+1 {
+				t.Errorf("got game IDs %+v, want 1, 2 and 3", games)
+			}
+		}
+
+		_, err = store.RedoGames(ctx, undone[0].ID)
+		assertError(t, err, poker.ErrUndoNotFound)
+		audit, err := store.GetAuditLog(ctx)
+		assertNoError(t, err)
+		assertAuditEntry(t, audit[len(audit)-1], poker.AuditRedo, []string{"Floyd", "Chris"}, "1")
+	})
+	t.Run("undos are audited", func(t *testing.T) {
+		store := newStore(t)
+		store.RecordWin("Chris")
+		store.RecordWin("Floyd")
+
+		_, err := store.UndoGames(ctx, 2)
+		assertNoError(t, err)
+
+		audit, err := store.GetAuditLog(ctx)
+		assertNoError(t, err)
+		if len(audit) != 1 {
+			t.Fatalf("got %d audit entries, want 1", len(audit))
+		}
+		assertAuditEntry(t, audit[0], poker.AuditUndo, []string{"Floyd", "Chris"}, "1")
+		if audit[0].Actor != "Cleo" {
+			t.Errorf("got actor %q, want Cleo", audit[0].Actor)
+		}
+	})
+}
+
+func TestUndoEndpoint(t *testing.T) {
+	newServer := func(t *testing.T) (*poker.PlayerServer, *poker.FileSystemPlayerStore) {
+		t.Helper()
+		return mustMakeFileSystemServer(t, `[]`)
+	}
+
+	t.Run("POST /undo takes back the last game", func(t *testing.T) {
+		server, store := newServer(t)
+		store.RecordWin("Chris")
+		store.RecordWin("Floyd")
+
+		response := serve(server, newRequest(http.MethodPost, "/undo"))
+		assertStatus(t, response.Code, http.StatusOK)
+
+		var undone []poker.GameResult
+		assertNoError(t, json.NewDecoder(response.Body).Decode(&undone))
+		if len(undone) != 1 || undone[0].Winner != "Floyd" {
+			t.Errorf("got undone games %+v, want Floyd's win", undone)
+		}
+		assertLeague(t, store.GetLeague(), poker.League{{"Chris", 1}})
+	})
+	t.Run("POST /undo?n= takes back the last n games", func(t *testing.T) {
+		server, store := newServer(t)
+		store.RecordWin("Chris")
+		store.RecordWin("Floyd")
+
+		assertStatus(t, serve(server, newRequest(http.MethodPost, "/undo?n=2")).Code, http.StatusForbidden)
+		assertLeague(t, store.GetLeague(), poker.League{{"Chris", 1}, {"Floyd", 1}})
+
+		assertStatus(t, serve(server, asAdmin(newRequest(http.MethodPost, "/undo?n=2"))).Code, http.StatusOK)
+		assertLeague(t, store.GetLeague(), poker.League{})
+	})
+	t.Run("GET /undone then POST /redo/{id} puts the games back", func(t *testing.T) {
+		server, store := newServer(t)
+		store.RecordWin("Chris")
+		serve(server, newRequest(http.MethodPost, "/undo"))
+
+		response := serve(server, newRequest(http.MethodGet, "/undone"))
+		assertStatus(t, response.Code, http.StatusOK)
+		var undone []poker.Undo
+		assertNoError(t, json.NewDecoder(response.Body).Decode(&undone))
+		if len(undone) != 1 || undone[0].Games[0].Winner != "Chris" {
+			t.Fatalf("got undone %+v, want Chris's win", undone)
+		}
+
+		redo := "/redo/" + strconv.Itoa(undone[0].ID)
+		assertStatus(t, serve(server, newRequest(http.MethodPost, redo)).Code, http.StatusForbidden)
+		assertStatus(t, serve(server, asAdmin(newRequest(http.MethodPost, redo))).Code, http.StatusOK)
+		assertLeague(t, store.GetLeague(), poker.League{{"Chris", 1}})
+
+		assertStatus(t, serve(server, asAdmin(newRequest(http.MethodPost, redo))).Code, http.StatusNotFound)
+		assertStatus(t, serve(server, asAdmin(newRequest(http.MethodPost, "/redo/last"))).Code, http.StatusBadRequest)
+	})
+	t.Run("returns errors for bad undos", func(t *testing.T) {
+		server, store := newServer(t)
+		cases := map[string]struct {
+			request *http.Request
+			want    int
+		}{
+			"nothing to undo": {newRequest(http.MethodPost, "/undo"), http.StatusConflict},
+			"not a number":    {newRequest(http.MethodPost, "/undo?n=two"), http.StatusBadRequest},
+			"too few":         {newRequest(http.MethodPost, "/undo?n=0"), http.StatusBadRequest},
+			"wrong method":    {newRequest(http.MethodGet, "/undo"), http.StatusMethodNotAllowed},
+		}
+		for name, c := range cases {
+			t.Run(name, func(t *testing.T) {
+				assertStatus(t, serve(server, c.request).Code, c.want)
+			})
+		}
+		assertLeague(t, store.GetLeague(), poker.League{})
+	})
+	t.Run("returns 501 when the store can't undo", func(t *testing.T) {
+		server := mustMakePlayerServer(t, &legacyPlayerStore{scores: map[string]int{}})
+		assertStatus(t, serve(server, newRequest(http.MethodPost, "/undo")).Code, http.StatusNotImplemented)
+		assertStatus(t, serve(server, newRequest(http.MethodGet, "/undone")).Code, http.StatusNotImplemented)
+		assertStatus(t, serve(server, newRequest(http.MethodPost, "/redo/1")).Code, http.StatusNotImplemented)
+	})
+}
+
+func TestRunUndoCommand(t *testing.T) {
+	ctx := context.Background()
+	store := mustMakeFileSystemStore(t, `[]`)
+	store.RecordWin("Chris")
+	store.RecordWin("Floyd")
+	store.RecordWin("Cleo")
+	out := &bytes.Buffer{}
+
+	assertNoError(t, poker.RunCommand(ctx, store, out, []string{"undo"}))
+	assertNoError(t, poker.RunCommand(ctx, store, out, []string{"undo", "2"}))
+
+	assertResponseBody(t, out.String(), "undid game 3 won by Cleo\nundid game 2 won by Floyd\nundid game 1 won by Chris\n")
+	assertError(t, poker.RunCommand(ctx, store, out, []string{"undo"}), poker.ErrNothingToUndo)
+	assertError(t, poker.RunCommand(ctx, store, out, []string{"undo", "all"}), poker.ErrInvalidUndo)
+}
@@ -0,0 +1,482 @@
+package poker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ProtocolVersion is the newest version of the game protocol spoken on /ws,
+// clients say which version they speak in their hello
+const ProtocolVersion = 1
+
+// MessageType says what a WSMessage is for
+type MessageType string
+
+const (
+	// MsgHello opens the protocol, from the client with its Version and,
+	// optionally, its Role or the Session it's reconnecting to
+	MsgHello MessageType = "hello"
+	// MsgWelcome accepts a hello with the Version the server will speak and
+	// the Session token to resume with, Resumed when the hello's was
+	MsgWelcome MessageType = "welcome"
+	// MsgStartGame starts a game for Players players
+	MsgStartGame MessageType = "start_game"
+	// MsgGameStarted confirms the game started with Players players, of
+	// whom PlayersLeft are still in, its blinds follow
+	MsgGameStarted MessageType = "game_started"
+	// MsgBlind is pushed by the server with each blind Amount and its Level
+	// as it's due, along with the NextAmount and how long until it's due,
+	// NextIn, unless it's the last
+	MsgBlind MessageType = "blind"
+	// MsgPlayerOut knocks a Player, whose name is optional, out of the game,
+	// everyone in the room is told along with how many PlayersLeft
+	MsgPlayerOut MessageType = "player_out"
+	// MsgDeclareWinner finishes the game with its Winner or Finishing order,
+	// an ID of the client's choosing stops a resent declaration counting twice
+	MsgDeclareWinner MessageType = "declare_winner"
+	// MsgGameFinished replies to a declared winner with the Game recorded
+	MsgGameFinished MessageType = "game_finished"
+	// MsgError replies to a message that couldn't be acted on
+	MsgError MessageType = "error"
+)
+
+// Codes of MsgError replies
+const (
+	WSErrorBadMessage         = "bad_message"
+	WSErrorUnsupportedVersion = "unsupported_version"
+	WSErrorGameInProgress     = "game_in_progress"
+	WSErrorNoGame             = "no_game"
+	WSErrorInvalidGame        = "invalid_game"
+	WSErrorStore              = "store_error"
+	WSErrorSpectator          = "spectator"
+	// WSErrorBusy is sent when the server has as many rooms or sessions
+	// open as it allows
+	WSErrorBusy = "busy"
+)
+
+// Roles a client says hello as, players are the default
+const (
+	// RolePlayer starts games, knocks players out and declares winners
+	RolePlayer = "player"
+	// RoleSpectator only follows the game in a room, for a clock on a TV
+	RoleSpectator = "spectator"
+)
+
+// WSMessage is every message of the game protocol, which fields are set
+// depends on its Type
+type WSMessage struct {
+	Type        MessageType `json:"type"`
+	Version     int         `json:"version,omitempty"`
+	Role        string      `json:"role,omitempty"`
+	Session     string      `json:"session,omitempty"`
+	Resumed     bool        `json:"resumed,omitempty"`
+	Players     int         `json:"players,omitempty"`
+	PlayersLeft int         `json:"players_left,omitempty"`
+	Player      string      `json:"player,omitempty"`
+	Level       int         `json:"level,omitempty"`
+	Amount      int         `json:"amount,omitempty"`
+	NextAmount  int         `json:"next_amount,omitempty"`
+	// NextIn is how many milliseconds until NextAmount is due
+	NextIn    int64       `json:"next_in,omitempty"`
+	ID        string      `json:"id,omitempty"`
+	Winner    string      `json:"winner,omitempty"`
+	Finishing []string    `json:"finishing,omitempty"`
+	Game      *GameResult `json:"game,omitempty"`
+	Code      string      `json:"code,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+var errHandshake = errors.New("websocket handshake failed")
+
+// errGameReplayed stops a room finishing its game when the declaration was
+// for a game already recorded
+var errGameReplayed = errors.New("game already recorded")
+
+// webSocket plays games with clients that open with a hello, in the room
+// given by ?room= or a room of their own. Clients from before the protocol
+// send a single winner, and are done.
+func (p *PlayerServer) webSocket(w http.ResponseWriter, r *http.Request) {
+	var room *gameRoom
+	if id := r.URL.Query().Get("room"); id != "" {
+		var err error
+		if room, err = p.rooms.find(id); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("problem upgrading connection to websocket: %v", err)
+		return
+	}
+	ws := &wsConn{Conn: conn}
+	defer ws.Close()
+	stop := ws.keepAlive(p.heartbeat)
+	defer stop()
+
+	_, message, err := ws.ReadMessage()
+	if err != nil {
+		logWSError(err)
+		return
+	}
+	var hello WSMessage
+	if err := json.Unmarshal(message, &hello); err != nil || hello.Type == "" {
+		p.recordLegacyWSGame(r.Context(), message)
+		return
+	}
+
+	session := &wsSession{server: p, conn: ws, room: room}
+	defer session.end()
+	if err := session.handshake(hello); err != nil {
+		logWSError(err)
+		return
+	}
+	if err := session.run(r.Context()); err != nil {
+		logWSError(err)
+	}
+}
+
+// recordLegacyWSGame records the game in the one message sent by a client
+// that doesn't speak the protocol
+func (p *PlayerServer) recordLegacyWSGame(ctx context.Context, message []byte) {
+	game, id := gameFromWSMessage(message)
+	_, replayed, err := p.recordWSGame(ctx, id, func() (GameResult, error) {
+		return game, p.recordWin(ctx, game)
+	})
+	if err != nil {
+		log.Printf("problem recording game from %s: %v", message, err)
+		return
+	}
+	if replayed {
+		log.Printf("ignoring game %s from websocket, already recorded", id)
+	}
+}
+
+// recordWSGame records a game sent over the websocket once per id. A game
+// already recorded under id isn't recorded again, it's returned as it was
+// and replayed is true.
+func (p *PlayerServer) recordWSGame(ctx context.Context, id string, record func() (GameResult, error)) (game GameResult, replayed bool, err error) {
+	if id == "" || p.idempotency == nil {
+		game, err = record()
+		return game, false, err
+	}
+
+	key := "ws:" + id
+	entry, first, err := p.idempotency.begin(key, "ws")
+	if err != nil {
+		return GameResult{}, false, err
+	}
+	if !first {
+		game, err = waitForWSGame(ctx, id, entry)
+		return game, err == nil, err
+	}
+
+	defer p.idempotency.finish(key, entry)
+	entry.status = http.StatusInternalServerError
+	if game, err = record(); err != nil {
+		return game, false, err
+	}
+	if entry.body, err = json.Marshal(game); err != nil {
+		return game, false, err
+	}
+	entry.status = http.StatusOK
+	return game, false, nil
+}
+
+// recordedWSGame returns the game already recorded under id, waiting for it
+// if it's being recorded, and false when no game has been sent with id
+func (p *PlayerServer) recordedWSGame(ctx context.Context, id string) (GameResult, bool, error) {
+	if id == "" || p.idempotency == nil {
+		return GameResult{}, false, nil
+	}
+	entry, ok := p.idempotency.lookup("ws:" + id)
+	if !ok {
+		return GameResult{}, false, nil
+	}
+	game, err := waitForWSGame(ctx, id, entry)
+	return game, err == nil, err
+}
+
+// waitForWSGame waits for the game recorded under id in entry
+func waitForWSGame(ctx context.Context, id string, entry *idempotentResponse) (game GameResult, err error) {
+	select {
+	case <-entry.done:
+	case <-ctx.Done():
+		return GameResult{}, ctx.Err()
+	}
+	if entry.status != http.StatusOK {
+		return GameResult{}, fmt.Errorf("game %s failed to record, send it again", id)
+	}
+	err = json.Unmarshal(entry.body, &game)
+	return game, err
+}
+
+// wsGame is a game sent over the websocket, before the protocol, with an ID
+// of the client's choosing so that sending it again doesn't record it twice
+type wsGame struct {
+	ID        string   `json:"id"`
+	Winner    string   `json:"winner"`
+	Finishing []string `json:"finishing"`
+}
+
+// gameFromWSMessage reads the winner's name, a JSON array of names in
+// finishing order or a JSON wsGame, along with the game's ID if it has one
+func gameFromWSMessage(message []byte) (GameResult, string) {
+	var finishing []string
+	if err := json.Unmarshal(message, &finishing); err == nil && len(finishing) > 0 {
+		return GameResult{Finishing: finishing, Source: SourceWeb}, ""
+	}
+	var game wsGame
+	if err := json.Unmarshal(message, &game); err == nil && (game.Winner != "" || len(game.Finishing) > 0) {
+		return GameResult{Winner: game.Winner, Finishing: game.Finishing, Source: SourceWeb}, game.ID
+	}
+	return GameResult{Winner: string(message), Source: SourceWeb}, ""
+}
+
+// logWSError logs anything but the client going away
+func logWSError(err error) {
+	if errors.Is(err, errHandshake) || websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		log.Printf("dropping websocket that stopped answering: %v", err)
+		return
+	}
+	log.Printf("problem with websocket: %v", err)
+}
+
+// wsConn is a websocket safe for a game's alerts and its replies to be
+// written at once
+type wsConn struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+func (c *wsConn) send(message WSMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.WriteJSON(message)
+}
+
+func (c *wsConn) sendError(code, reason string) error {
+	return c.send(WSMessage{Type: MsgError, Code: code, Error: reason})
+}
+
+// wsSession plays the games of a room over one websocket. Its token lets
+// the client resume it, in the same room, over another websocket.
+type wsSession struct {
+	server    *PlayerServer
+	conn      *wsConn
+	version   int
+	room      *gameRoom
+	private   bool
+	spectator bool
+	token     string
+}
+
+// handshake welcomes a client whose hello asks for a version the server
+// speaks, anyone else is told why not and errHandshake returned
+func (s *wsSession) handshake(hello WSMessage) error {
+	if hello.Type != MsgHello {
+		if err := s.conn.sendError(WSErrorBadMessage, "the first message must be a hello"); err != nil {
+			return err
+		}
+		return errHandshake
+	}
+	if hello.Version < 1 || hello.Version > ProtocolVersion {
+		reason := fmt.Sprintf("protocol version %d isn't supported, the server speaks 1 to %d", hello.Version, ProtocolVersion)
+		if err := s.conn.sendError(WSErrorUnsupportedVersion, reason); err != nil {
+			return err
+		}
+		return errHandshake
+	}
+	switch hello.Role {
+	case "", RolePlayer:
+	case RoleSpectator:
+		s.spectator = true
+	default:
+		if err := s.conn.sendError(WSErrorBadMessage, fmt.Sprintf("unknown role %q", hello.Role)); err != nil {
+			return err
+		}
+		return errHandshake
+	}
+	s.version = hello.Version
+
+	resumed := s.resume(hello.Session)
+	if !resumed {
+		if s.spectator && s.room == nil {
+			if err := s.conn.sendError(WSErrorBadMessage, "spectators follow a room, join one with ?room="); err != nil {
+				return err
+			}
+			return errHandshake
+		}
+		err := s.open()
+		if errors.Is(err, ErrTooManyRooms) || errors.Is(err, errTooManySessions) {
+			if err := s.conn.sendError(WSErrorBusy, err.Error()); err != nil {
+				return err
+			}
+			return errHandshake
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return s.conn.send(WSMessage{Type: MsgWelcome, Version: s.version, Session: s.token, Resumed: resumed})
+}
+
+// resume carries on the session token was given for, in its room, returning
+// false when it can't be resumed
+func (s *wsSession) resume(token string) bool {
+	if token == "" || s.server.sessions == nil {
+		return false
+	}
+	resumed, ok := s.server.sessions.resume(token, s.conn)
+	if !ok {
+		return false
+	}
+	s.room, s.private, s.spectator, s.token = resumed.room, resumed.private, resumed.spectator, token
+	return true
+}
+
+// open starts a new session, in a room of its own unless one was asked for
+func (s *wsSession) open() error {
+	if s.room == nil {
+		room, err := s.server.rooms.private()
+		if err != nil {
+			return err
+		}
+		s.room, s.private = room, true
+	}
+	if s.server.sessions == nil {
+		return nil
+	}
+	token, err := s.server.sessions.open(s.conn, s.room, s.private, s.spectator)
+	if err != nil {
+		return err
+	}
+	s.token = token
+	return nil
+}
+
+// end lets the session be resumed once its websocket has gone, a room of
+// its own is closed when it can't be. It's safe to call whether or not the
+// handshake got as far as opening a session.
+func (s *wsSession) end() {
+	if s.token != "" {
+		s.server.sessions.drop(s.token, s.conn)
+		return
+	}
+	if s.private {
+		s.room.close()
+	}
+}
+
+// run joins the room and acts on every message until the client goes away,
+// returning why
+func (s *wsSession) run(ctx context.Context) error {
+	joined, err := s.room.join(s.conn)
+	defer s.room.leave(s.conn)
+	if err != nil || !joined {
+		return err
+	}
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var message WSMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			err = s.conn.sendError(WSErrorBadMessage, "messages must be JSON: "+err.Error())
+		} else {
+			err = s.handle(ctx, message)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle acts on message, returning an error only when replying failed
+func (s *wsSession) handle(ctx context.Context, message WSMessage) error {
+	switch message.Type {
+	case MsgStartGame, MsgPlayerOut, MsgDeclareWinner:
+		if s.spectator {
+			return s.conn.sendError(WSErrorSpectator, "spectators can only follow the game")
+		}
+	}
+
+	switch message.Type {
+	case MsgStartGame:
+		return s.startGame(message)
+	case MsgPlayerOut:
+		return s.playerOut(message)
+	case MsgDeclareWinner:
+		return s.declareWinner(ctx, message)
+	case MsgHello:
+		return s.conn.sendError(WSErrorBadMessage, "already said hello")
+	}
+	return s.conn.sendError(WSErrorBadMessage, fmt.Sprintf("unknown message type %q", message.Type))
+}
+
+func (s *wsSession) startGame(message WSMessage) error {
+	if message.Players < 1 {
+		return s.conn.sendError(WSErrorBadMessage, "players must be a positive number")
+	}
+	if err := s.room.start(message.Players); err != nil {
+		return s.conn.sendError(WSErrorGameInProgress, err.Error())
+	}
+	return nil
+}
+
+func (s *wsSession) playerOut(message WSMessage) error {
+	err := s.room.knockOut(message.Player)
+	switch {
+	case errors.Is(err, ErrNoGame):
+		return s.conn.sendError(WSErrorNoGame, err.Error())
+	case err != nil:
+		return s.conn.sendError(WSErrorInvalidGame, err.Error())
+	}
+	return nil
+}
+
+func (s *wsSession) declareWinner(ctx context.Context, message WSMessage) error {
+	// a declaration sent again is answered with the game it recorded, only
+	// to its sender, whatever the room is playing now
+	recorded, replayed, err := s.server.recordedWSGame(ctx, message.ID)
+	if err == nil && !replayed {
+		err = s.room.finish(ctx, func(game *PokerGame) (GameResult, error) {
+			var recordErr error
+			recorded, replayed, recordErr = s.server.recordWSGame(ctx, message.ID, func() (GameResult, error) {
+				return game.FinishGame(ctx, GameResult{Winner: message.Winner, Finishing: message.Finishing})
+			})
+			if recordErr == nil && replayed {
+				return recorded, errGameReplayed
+			}
+			return recorded, recordErr
+		})
+	}
+	switch {
+	case replayed:
+		return s.conn.send(WSMessage{Type: MsgGameFinished, Game: &recorded})
+	case errors.Is(err, ErrNoGame):
+		return s.conn.sendError(WSErrorNoGame, err.Error())
+	case errors.Is(err, ErrInvalidGame):
+		return s.conn.sendError(WSErrorInvalidGame, err.Error())
+	case err != nil:
+		log.Printf("problem recording game from websocket: %v", err)
+		return s.conn.sendError(WSErrorStore, "the game couldn't be recorded, declare the winner again")
+	}
+	return nil
+}
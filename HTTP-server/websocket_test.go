@@ -0,0 +1,206 @@
+package poker_test
+
+import (
+	poker "HTTP-server"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketGameProtocol(t *testing.T) {
+	newServer := func(t *testing.T, store *poker.StubPlayerStore) string {
+		t.Helper()
+		server := httptest.NewServer(mustMakePlayerServer(t, store))
+		t.Cleanup(server.Close)
+		return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	}
+
+	t.Run("a whole game is started, alerted and won", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		ws := mustHandshake(t, newServer(t, store))
+
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgStartGame, Players: 3})
+		assertWSMessage(t, readWS(t, ws), poker.WSMessage{Type: poker.MsgGameStarted, Players: 3, PlayersLeft: 3})
+		assertFirstBlind(t, readWS(t, ws), 8*time.Minute)
+
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgDeclareWinner, Finishing: []string{"Cleo", "Chris", "Floyd"}})
+		finished := readWS(t, ws)
+		if finished.Type != poker.MsgGameFinished || finished.Game == nil || finished.Game.Winner != "Cleo" || finished.Game.Players != 3 {
+			t.Fatalf("got %+v, want Cleo's game finished", finished)
+		}
+		if finished.Game.Source != poker.SourceWeb {
+			t.Errorf("got source %q, want %q", finished.Game.Source, poker.SourceWeb)
+		}
+		poker.AssertPlayerWin(t, store, "Cleo")
+	})
+	t.Run("another game can be played once one finishes", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		ws := mustHandshake(t, newServer(t, store))
+
+		for _, winner := range []string{"Cleo", "Chris"} {
+			sendWS(t, ws, poker.WSMessage{Type: poker.MsgStartGame, Players: 2})
+			readWSUntil(t, ws, poker.MsgGameStarted)
+			sendWS(t, ws, poker.WSMessage{Type: poker.MsgDeclareWinner, Winner: winner})
+			readWSUntil(t, ws, poker.MsgGameFinished)
+		}
+
+		if want := []string{"Cleo", "Chris"}; !reflect.DeepEqual(store.WinCalls, want) {
+			t.Errorf("got wins %v, want %v", store.WinCalls, want)
+		}
+	})
+	t.Run("a declaration sent again with its id is answered with the game it recorded", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		ws := mustHandshake(t, newServer(t, store))
+
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgStartGame, Players: 2})
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgDeclareWinner, ID: "game-1", Winner: "Cleo"})
+		first := readWSUntil(t, ws, poker.MsgGameFinished)
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgDeclareWinner, ID: "game-1", Winner: "Cleo"})
+		again := readWSUntil(t, ws, poker.MsgGameFinished)
+		if !reflect.DeepEqual(first.Game, again.Game) {
+			t.Errorf("got %+v resent, want the first game %+v", again.Game, first.Game)
+		}
+
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgStartGame, Players: 5})
+		readWSUntil(t, ws, poker.MsgGameStarted)
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgDeclareWinner, ID: "game-1", Winner: "Cleo"})
+		again = readWSUntil(t, ws, poker.MsgGameFinished)
+		if !reflect.DeepEqual(first.Game, again.Game) {
+			t.Errorf("got %+v resent mid game, want the first game %+v", again.Game, first.Game)
+		}
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgPlayerOut, Player: "Chris"})
+		if out := readWSUntil(t, ws, poker.MsgPlayerOut); out.PlayersLeft != 4 {
+			t.Errorf("got %+v, want the 5 player game still going with 4 left", out)
+		}
+		poker.AssertPlayerWin(t, store, "Cleo")
+	})
+	t.Run("bad messages get error replies and the connection carries on", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		ws := mustHandshake(t, newServer(t, store))
+
+		cases := []struct {
+			send string
+			code string
+		}{
+			{`not json`, poker.WSErrorBadMessage},
+			{`{"type": "juggle"}`, poker.WSErrorBadMessage},
+			{`{"type": "declare_winner", "winner": "Cleo"}`, poker.WSErrorNoGame},
+			{`{"type": "start_game", "players": 0}`, poker.WSErrorBadMessage},
+			{`{"type": "start_game", "players": 2}`, ""},
+			{`{"type": "start_game", "players": 2}`, poker.WSErrorGameInProgress},
+			{`{"type": "declare_winner", "winner": "Cleo", "finishing": ["Chris"]}`, poker.WSErrorInvalidGame},
+		}
+		for _, c := range cases {
+			writeWSMessage(t, ws, c.send)
+			if c.code == "" {
+				readWSUntil(t, ws, poker.MsgGameStarted)
+				continue
+			}
+			got := readWSUntil(t, ws, poker.MsgError)
+			if got.Code != c.code || got.Error == "" {
+				t.Errorf("sending %s got error %+v, want code %s", c.send, got, c.code)
+			}
+		}
+		if len(store.WinCalls) != 0 {
+			t.Errorf("got wins %v, want none", store.WinCalls)
+		}
+	})
+	t.Run("a store failure is reported and the winner can be declared again", func(t *testing.T) {
+		store := &poker.StubPlayerStore{Err: errors.New("disk on fire")}
+		ws := mustHandshake(t, newServer(t, store))
+
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgStartGame, Players: 2})
+		sendWS(t, ws, poker.WSMessage{Type: poker.MsgDeclareWinner, ID: "game-1", Winner: "Cleo"})
+		if got := readWSUntil(t, ws, poker.MsgError); got.Code != poker.WSErrorStore {
+			t.Errorf("got error %+v, want a store error", got)
+		}
+	})
+	t.Run("the handshake refuses versions the server doesn't speak", func(t *testing.T) {
+		url := newServer(t, &poker.StubPlayerStore{})
+		for _, hello := range []poker.WSMessage{
+			{Type: poker.MsgHello, Version: poker.ProtocolVersion + 1},
+			{Type: poker.MsgHello},
+			{Type: poker.MsgStartGame, Players: 2},
+		} {
+			ws := mustDialWS(t, url)
+			sendWS(t, ws, hello)
+			if got := readWS(t, ws); got.Type != poker.MsgError {
+				t.Errorf("sending %+v got %+v, want an error", hello, got)
+			}
+			if _, _, err := ws.ReadMessage(); err == nil {
+				t.Errorf("expected the connection closed after a failed handshake")
+			}
+			ws.Close()
+		}
+	})
+}
+
+// mustHandshake dials url and says hello, failing unless welcomed
+func mustHandshake(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	ws, _ := mustSayHello(t, url, poker.WSMessage{Type: poker.MsgHello, Version: poker.ProtocolVersion})
+	return ws
+}
+
+// mustSayHello dials url and sends hello, failing unless welcomed with a
+// session, and returns the welcome
+func mustSayHello(t *testing.T, url string, hello poker.WSMessage) (*websocket.Conn, poker.WSMessage) {
+	t.Helper()
+	ws := mustDialWS(t, url)
+	t.Cleanup(func() { ws.Close() })
+	sendWS(t, ws, hello)
+	welcome := readWS(t, ws)
+	if welcome.Type != poker.MsgWelcome || welcome.Version != poker.ProtocolVersion || welcome.Session == "" {
+		t.Fatalf("got %+v, want welcomed to version %d with a session", welcome, poker.ProtocolVersion)
+	}
+	return ws, welcome
+}
+
+func sendWS(t testing.TB, ws *websocket.Conn, message poker.WSMessage) {
+	t.Helper()
+	if err := ws.WriteJSON(message); err != nil {
+		t.Fatalf("could not send %+v over ws connection %v", message, err)
+	}
+}
+
+func readWS(t testing.TB, ws *websocket.Conn) poker.WSMessage {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	var message poker.WSMessage
+	if err := ws.ReadJSON(&message); err != nil {
+		t.Fatalf("could not read from ws connection %v", err)
+	}
+	return message
+}
+
+// readWSUntil skips messages, such as blinds, until one of type arrives
+func readWSUntil(t testing.TB, ws *websocket.Conn, messageType poker.MessageType) poker.WSMessage {
+	t.Helper()
+	for {
+		if message := readWS(t, ws); message.Type == messageType {
+			return message
+		}
+	}
+}
+
+// assertFirstBlind checks blind is the first blind of a game whose blinds go
+// up every interval
+func assertFirstBlind(t testing.TB, blind poker.WSMessage, interval time.Duration) {
+	t.Helper()
+	nextIn := time.Duration(blind.NextIn) * time.Millisecond
+	if blind.Type != poker.MsgBlind || blind.Level != 1 || blind.Amount != 100 || blind.NextAmount != 200 || nextIn > interval || nextIn < interval-time.Second {
+		t.Errorf("got %+v, want level 1, a blind of 100 going up to 200 in %v", blind, interval)
+	}
+}
+
+func assertWSMessage(t testing.TB, got, want poker.WSMessage) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got message %+v, want %+v", got, want)
+	}
+}